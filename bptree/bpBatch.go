@@ -0,0 +1,225 @@
+package bpTree
+
+import (
+	"slices"
+	"sort"
+)
+
+// ➡️ batch operation
+
+// OpKind tells whether an OpG inserts or deletes.
+type OpKind int
+
+const (
+	OpInsert OpKind = iota + 1 // Insert the item.
+	OpDelete                   // Delete one item with the key of the item.
+)
+
+// OpG is one operation of ApplyBatch.
+type OpG[K, V any] struct {
+	Kind OpKind        // Insert or delete.
+	Item BpItemG[K, V] // The item to insert, or the item whose key is deleted.
+}
+
+// OpG with int64 keys.
+type Op = OpG[int64, any]
+
+// OpsFromSigned turns a signed stream into operations, the same encoding the test models produce:
+// a value >= 0 inserts the key, a negative value deletes the key -value. (正数新增，负数删除)
+func OpsFromSigned(values []int64) (ops []Op) {
+	ops = make([]Op, 0, len(values))
+	for _, value := range values {
+		if value >= 0 {
+			ops = append(ops, Op{Kind: OpInsert, Item: BpItem{Key: value}})
+		} else {
+			ops = append(ops, Op{Kind: OpDelete, Item: BpItem{Key: -value}})
+		}
+	}
+	return
+}
+
+// ApplyBatch ensures thread safety, applies the operations in key order, release lock.
+// The batch is sorted by key first; the operations on the same key keep their order, so the result is the same as
+// applying them one by one. The lock is taken once, and the runs of keys that land in the same data node are applied
+// there directly as long as no split or merge is needed; only the rest go through the normal insertion and deletion.
+// (排序后一次上锁，同一个资料节点的连续操作直接处理，需要分裂或合拼时才走一般流程)
+//
+// Deleting a missing key is skipped, and so is inserting an existing key in unique mode; the counts tell how many
+// operations took effect. When the index is corrupted, it stops with an error matching ErrIndexCorrupted,
+// and the operations before it stay applied.
+func (tree *BpTreeG[K, V]) ApplyBatch(ops []OpG[K, V]) (inserted, deleted int, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Sort a copy by key and then by position, so that the caller's slice is not changed
+	// and the operations on the same key keep their order.
+	order := make([]int, len(ops))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		if diff := tree.cfg.compare(ops[a].Item.Key, ops[b].Item.Key); diff != 0 {
+			return diff
+		}
+		return a - b
+	})
+	sorted := make([]OpG[K, V], len(ops))
+	for i, pos := range order {
+		sorted[i] = ops[pos]
+	}
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Log the sorted batch as one record before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walBatch, ops: sorted}); err != nil {
+		return
+	}
+
+	// Performing the batch.
+	inserted, deleted, err = tree.applyBatch(sorted)
+
+	// Performing a return.
+	return
+}
+
+// applyBatch applies the operations sorted by key, the caller holds the lock.
+func (tree *BpTreeG[K, V]) applyBatch(sorted []OpG[K, V]) (inserted, deleted int, err error) {
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	for i := 0; i < len(sorted); {
+		// Apply the run that lands in one data node directly.
+		n, runInserted, runDeleted := tree.applyRun(sorted[i:])
+		inserted, deleted, i = inserted+runInserted, deleted+runDeleted, i+n
+		if n > 0 || i == len(sorted) {
+			continue
+		}
+
+		// The operation needs a split or a merge, so it goes through the normal way.
+		op := sorted[i]
+		switch op.Kind {
+		case OpInsert:
+			if tree.cfg.unique {
+				if _, found := tree.root.search(tree.cfg, op.Item.Key); found {
+					break
+				}
+			}
+			if err = tree.insert(op.Item); err != nil {
+				return
+			}
+			inserted++
+		case OpDelete:
+			var removed bool
+			if removed, _, _, err = tree.delAndDir(op.Item); err != nil {
+				return
+			}
+			if removed {
+				deleted++
+			}
+		}
+		i++
+	}
+
+	// Performing a return.
+	return
+}
+
+// applyRun applies the leading operations that land in the same data node without splitting or merging it,
+// and returns how many operations it handled. The caller holds the lock.
+func (tree *BpTreeG[K, V]) applyRun(ops []OpG[K, V]) (n, inserted, deleted int) {
+	// In lazy deletion mode, every operation goes through the normal way, which masks and revives the items.
+	if tree.cfg.lazy {
+		return
+	}
+
+	// Clone the path in copy-on-write mode, all keys of the run share it.
+	tree.own(ops[0].Item.Key)
+
+	// Find the data node and the index key on its right, the keys of the data node stay below it.
+	data, upper, bounded := tree.root.dataWithUpper(tree.cfg, ops[0].Item.Key)
+
+	// The run stays in one data node, so the counts along the path of the first key cover it.
+	defer tree.root.recount(tree.cfg, ops[0].Item.Key)
+
+	for ; n < len(ops); n++ {
+		op := ops[n]
+		if bounded && tree.cfg.compare(op.Item.Key, upper) >= 0 {
+			return
+		}
+
+		// The position of the first item not less than the key.
+		ix := sort.Search(len(data.Items), func(i int) bool {
+			return tree.cfg.compare(data.Items[i].Key, op.Item.Key) >= 0
+		})
+		exists := ix < len(data.Items) && tree.cfg.compare(data.Items[ix].Key, op.Item.Key) == 0
+
+		switch op.Kind {
+		case OpInsert:
+			// In unique mode, a key in the range of this data node can only be in this data node.
+			if tree.cfg.unique && exists {
+				continue
+			}
+			// Stop before a split, or before the first key of a data node changes, which the index refers to.
+			if len(data.Items)+1 >= tree.cfg.width || (ix == 0 && !exists && data.Previous != nil) {
+				return
+			}
+			// A new duplicate goes after the existing ones, the same as a single insertion.
+			at := ix + sort.Search(len(data.Items)-ix, func(i int) bool {
+				return tree.cfg.compare(data.Items[ix+i].Key, op.Item.Key) > 0
+			})
+			// Neighbors may share the backing array after borrowing, so the insertion always makes a new one.
+			data.Items = slices.Insert(slices.Clip(data.Items), at, op.Item)
+			inserted++
+		case OpDelete:
+			// The newest duplicate is removed, the same one a single deletion removes.
+			// All duplicates of a key below the upper index key are in this data node.
+			last := ix + sort.Search(len(data.Items)-ix, func(i int) bool {
+				return tree.cfg.compare(data.Items[ix+i].Key, op.Item.Key) > 0
+			}) - 1
+			// Stop when the key is elsewhere, when the first key of the data node would change,
+			// or when the data node would fall below the minimum and has to be joined with a neighbor.
+			if !exists || last == 0 || len(data.Items) <= tree.cfg.minItems() {
+				return
+			}
+			// Neighbors may share the backing array after borrowing, so the deletion makes a new one.
+			data.Items = append(slices.Clip(data.Items[:last]), data.Items[last+1:]...)
+			deleted++
+		default:
+			// An unknown kind is skipped.
+		}
+	}
+
+	// Performing a return.
+	return
+}
+
+// dataWithUpper descends the same way as insertItem and returns the data node for the key,
+// together with the nearest index key on its right when there is one.
+func (inode *BpIndexG[K, V]) dataWithUpper(cfg *bpConfig[K], key K) (data *BpDataG[K, V], upper K, bounded bool) {
+	current := inode
+	for {
+		// No equal sign, so equal keys go to the right.
+		ix := sort.Search(len(current.Index), func(i int) bool {
+			return cfg.compare(current.Index[i], key) > 0
+		})
+		if ix < len(current.Index) {
+			upper, bounded = current.Index[ix], true
+		}
+
+		// Descend into the index nodes.
+		if len(current.IndexNodes) > 0 {
+			ix = min(ix, len(current.IndexNodes)-1)
+			current = current.IndexNodes[ix]
+			continue
+		}
+
+		// Reach the data nodes.
+		data = current.DataNodes[min(ix, len(current.DataNodes)-1)]
+		return
+	}
+}
@@ -0,0 +1,162 @@
+package bpTree
+
+import (
+	"iter"
+)
+
+// ➡️ bulk load operation
+
+// BulkLoad ensures thread safety, replaces the content of B plus tree with the sorted items, release lock.
+// The data nodes and the index levels are built from the bottom up in O(n), without any split.
+// The fill factor in (0, 1] decides how full the nodes are; 1 fills them up to the width minus one,
+// a smaller value leaves room for later insertions, but the nodes are never filled below the half a split leaves.
+// (由下往上建树，填充率决定节点留多少空位)
+// The items must be in ascending order, and in unique mode no key may repeat.
+// On error the tree is left unchanged.
+func (tree *BpTreeG[K, V]) BulkLoad(items []BpItemG[K, V], fillFactor float64) (err error) {
+	return tree.BulkLoadSeq(func(yield func(K, V) bool) {
+		for _, item := range items {
+			if !yield(item.Key, item.Val) {
+				return
+			}
+		}
+	}, fillFactor)
+}
+
+// BulkLoadSeq is BulkLoad for a sequence of keys and values, such as the All method of another tree
+// or a reader of a sorted file.
+func (tree *BpTreeG[K, V]) BulkLoadSeq(items iter.Seq2[K, V], fillFactor float64) (err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Check the fill factor before taking the lock.
+	if fillFactor <= 0 || fillFactor > 1 {
+		err = ErrInvalidFillFactor
+		return
+	}
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Collect the items, the tree is not touched until everything has been checked.
+	all, err := tree.sortedItems(items)
+	if err != nil {
+		return
+	}
+
+	// Log the items before loading them.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walLoad, items: all, fill: fillFactor}); err != nil {
+		return
+	}
+
+	// Performing the loading.
+	tree.load(all, fillFactor)
+
+	// Performing a return.
+	return
+}
+
+// load replaces the content of B plus tree with the sorted items, the caller holds the lock.
+func (tree *BpTreeG[K, V]) load(items []BpItemG[K, V], fillFactor float64) {
+	// Build the data nodes first.
+	dataNodes := tree.buildDataNodes(items, fillFactor)
+
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	// No item at all leaves an empty root, the same as a new tree.
+	if len(dataNodes) == 0 {
+		tree.root = &BpIndexG[K, V]{DataNodes: []*BpDataG[K, V]{{gen: tree.gen}}, gen: tree.gen}
+		return
+	}
+
+	// The bottom index nodes group the data nodes.
+	perNode := max(2, int(fillFactor*float64(tree.cfg.width))) // The children of an index node; the index keys stay below the width.
+	var level []*BpIndexG[K, V]
+	for _, group := range groups(len(dataNodes), perNode, tree.cfg.halfWidth) {
+		inode := &BpIndexG[K, V]{Index: []K{}, DataNodes: dataNodes[group[0]:group[1]:group[1]], gen: tree.gen}
+		for _, data := range inode.DataNodes[1:] {
+			inode.Index = append(inode.Index, data.Items[0].Key)
+		}
+		level = append(level, inode)
+	}
+
+	// The upper index levels group the nodes below until one root is left. (一层一层往上建)
+	for len(level) > 1 {
+		var upper []*BpIndexG[K, V]
+		for _, group := range groups(len(level), perNode, tree.cfg.halfWidth) {
+			inode := &BpIndexG[K, V]{Index: []K{}, IndexNodes: level[group[0]:group[1]:group[1]], gen: tree.gen}
+			for _, child := range inode.IndexNodes[1:] {
+				inode.Index = append(inode.Index, child.edgeValue())
+			}
+			upper = append(upper, inode)
+		}
+		level = upper
+	}
+	tree.root = level[0]
+
+	// Count the items of every subtree.
+	tree.root.recountAll(tree.cfg)
+}
+
+// sortedItems collects the items and checks their order.
+func (tree *BpTreeG[K, V]) sortedItems(items iter.Seq2[K, V]) (all []BpItemG[K, V], err error) {
+	for key, val := range items {
+		if n := len(all); n > 0 {
+			switch diff := tree.cfg.compare(all[n-1].Key, key); {
+			case diff > 0:
+				err = ErrNotSorted
+				return
+			case diff == 0 && tree.cfg.unique:
+				err = ErrDuplicateKey
+				return
+			}
+		}
+		all = append(all, BpItemG[K, V]{Key: key, Val: val})
+	}
+
+	// Performing a return.
+	return
+}
+
+// buildDataNodes puts the sorted items into linked data nodes.
+func (tree *BpTreeG[K, V]) buildDataNodes(all []BpItemG[K, V], fillFactor float64) (dataNodes []*BpDataG[K, V]) {
+	// A data node holds fewer items than the width.
+	perNode := max(1, int(fillFactor*float64(tree.cfg.width-1)))
+	var previous *BpDataG[K, V]
+	for _, group := range groups(len(all), perNode, tree.cfg.minItems()) {
+		data := &BpDataG[K, V]{Items: all[group[0]:group[1]:group[1]], Previous: previous, gen: tree.gen}
+		if previous != nil {
+			previous.Next = data
+		}
+		dataNodes = append(dataNodes, data)
+		previous = data
+	}
+
+	// Performing a return.
+	return
+}
+
+// groups splits n entries into ranges [from, to) of at most perNode entries, with the sizes spread evenly.
+// Every range keeps at least least entries unless n itself is smaller, because the nodes below the root
+// must not start under the minimum a split leaves; then the ranges grow beyond perNode instead.
+// (平均分配，每个节点不少于分裂后的最小数量)
+func groups(n, perNode, least int) (ranges [][2]int) {
+	if n == 0 {
+		return
+	}
+	count := max(1, min((n+perNode-1)/perNode, n/least))
+	for i, from := 0, 0; i < count; i++ {
+		to := from + n/count
+		if i < n%count {
+			to++
+		}
+		ranges = append(ranges, [2]int{from, to})
+		from = to
+	}
+	return
+}
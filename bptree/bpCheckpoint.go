@@ -0,0 +1,422 @@
+package bpTree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ➡️ checkpoint operation
+
+// The checkpoint is a dump of the unmasked items in ascending order, and a manifest next to it.
+//
+//	dump      magic, byte order flag and version, then every item as the key after its uint16 length
+//	          and the value after its uint32 length, then the number of items and a CRC-32C of everything before it.
+//	manifest  CheckpointManifest in JSON, written after the dump, so a dump without a manifest is incomplete.
+//
+// Every checkpoint writes a dump of its own, named after the path with the next generation, path.1, path.2 and so on.
+// The manifest at path.manifest names the dump, and it is replaced last, so a crash at any point leaves
+// the manifest of the last complete checkpoint together with its dump. (每次写新的档案，最后才切换清单)
+
+const (
+	checkpointHeaderSize  = 10       // The magic, the byte order flag and the version.
+	checkpointTrailerSize = 12       // The number of items and the checksum.
+	checkpointBufferSize  = 64 << 10 // The buffer of the writer of a dump.
+	manifestSuffix        = ".manifest"
+)
+
+// checkpointMagic starts every dump.
+var checkpointMagic = [8]byte{'B', 'P', 'T', 'R', 'E', 'E', 'C', 'K'}
+
+// CheckpointManifest describes a checkpoint, and where the write-ahead log was when it was taken.
+type CheckpointManifest struct {
+	Dump     string `json:"dump"`     // The file name of the dump, in the directory of the manifest.
+	Items    uint64 `json:"items"`    // The number of items in the dump.
+	Size     int64  `json:"size"`     // The bytes of the dump.
+	Checksum uint32 `json:"checksum"` // The CRC-32C at the end of the dump.
+
+	// Where the write-ahead log was when the checkpoint was taken, the records before the offset are in the dump,
+	// and RecoverCheckpoint replays the ones after it. They are empty without WithWriteAheadLog.
+	Segment   string `json:"segment,omitempty"`   // The file name of the segment, in the directory of the tree file.
+	Offset    int64  `json:"offset,omitempty"`    // The bytes of the segment the checkpoint covers.
+	ByteOrder string `json:"byteOrder,omitempty"` // The byte order of the records, the one of the tree file.
+}
+
+// Checkpoint ensures thread safety, writes the unmasked items to a new dump beside path and a manifest at path.manifest,
+// release lock. The dump streams through the data nodes into a buffered writer and is synced before it is renamed,
+// and BulkLoad rebuilds a tree from it with LoadCheckpoint. The dump of the checkpoint before is removed afterward.
+// With a write-ahead log, the segments before the one in the manifest are removed, and Flush keeps the ones after it
+// for RecoverCheckpoint. (旧的日志段可以删除)
+// The read lock is held while the dump is written, lookups go on and modifications wait. (只读锁，查询不受影响)
+// Two checkpoints to the same path must not run at the same time.
+func (tree *BpTreeG[K, V]) Checkpoint(path string) (manifest CheckpointManifest, err error) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// The items are encoded the same way as in the pages of a file.
+	store, err := tree.dumpStore()
+	if err != nil {
+		return
+	}
+
+	// No record is appended while the read lock is held, so the position matches the dump.
+	var segment uint64
+	if tree.file != nil && tree.file.log != nil {
+		segment, manifest.Offset = tree.file.log.position()
+		manifest.Segment = filepath.Base(segmentPath(tree.file.log.path, segment))
+		manifest.ByteOrder = tree.file.header.order.String()
+	}
+
+	// The dump takes the next generation, the one of the last checkpoint stays until the new manifest is in place.
+	last, _ := readManifest(path)
+	manifest.Dump = nextDump(path, last.Dump)
+	dump := filepath.Join(filepath.Dir(path), manifest.Dump)
+
+	// Write the dump beside its final name, and rename it once it is complete.
+	temp := dump + ".tmp"
+	if manifest.Items, manifest.Size, manifest.Checksum, err = store.dump(tree, temp); err != nil {
+		_ = os.Remove(temp)
+		return
+	}
+	if err = renameSynced(temp, dump); err != nil {
+		return
+	}
+
+	// ⚠️ The manifest is written last, the same as the header of a file.
+	if err = writeManifest(path+manifestSuffix, manifest); err != nil {
+		return
+	}
+
+	// The dump of the last checkpoint is no longer named by the manifest.
+	if last.Dump != "" && last.Dump != manifest.Dump {
+		if err = os.Remove(filepath.Join(filepath.Dir(path), last.Dump)); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	// The records before the checkpoint are in the dump, so the segments before its own are no longer needed.
+	// The segment stays the current one while the read lock is held.
+	if manifest.Segment != "" {
+		tree.file.log.retain(segment)
+		err = removeSegments(tree.file.log.path, segment, segment)
+	}
+
+	// Performing a return.
+	return
+}
+
+// nextDump returns the file name of the dump after the last one, path.1 when there is none.
+func nextDump(path, last string) string {
+	base := filepath.Base(path)
+	generation := 0
+	if suffix, ok := strings.CutPrefix(last, base+"."); ok {
+		generation, _ = strconv.Atoi(suffix)
+	}
+	return base + "." + strconv.Itoa(generation+1)
+}
+
+// LoadCheckpoint replaces the content of the tree with the checkpoint at path, with BulkLoad and the fill factor.
+// It returns the manifest, and ErrCorruptCheckpoint when the dump is incomplete, does not match its manifest,
+// or the manifest names a dump outside its own directory.
+// On error the tree is left unchanged.
+func (tree *BpTreeG[K, V]) LoadCheckpoint(path string, fillFactor float64) (manifest CheckpointManifest, err error) {
+	// Read the manifest, then the dump it describes.
+	if manifest, err = readManifest(path); err != nil {
+		return
+	}
+	tree.mutex.RLock()
+	store, err := tree.dumpStore()
+	tree.mutex.RUnlock()
+	if err != nil {
+		return
+	}
+	items, err := store.undump(filepath.Join(filepath.Dir(path), manifest.Dump), manifest)
+	if err != nil {
+		return
+	}
+
+	// Performing the loading.
+	err = tree.BulkLoad(items, fillFactor)
+
+	// Performing a return.
+	return
+}
+
+// RecoverCheckpoint replaces the content of the tree with the checkpoint at path, the same as LoadCheckpoint,
+// then replays the write-ahead log of the tree file at treePath on top of it: the records of the segment
+// in the manifest after its offset, then the segments of the later versions, which Flush keeps for it.
+// Together they bring back every change logged after the checkpoint, the same ones Open brings back from the file.
+// (检查点加上之后的日志)
+// The tree should have the key mode and the deletion mode of the tree that took the checkpoint. The records are
+// applied without being logged again, so a tree opened by Open keeps them with Flush.
+// On a replay error, the tree holds the checkpoint and the records before the error.
+func (tree *BpTreeG[K, V]) RecoverCheckpoint(path, treePath string, fillFactor float64) (manifest CheckpointManifest, err error) {
+	// Load the checkpoint first, nothing is replayed without a log.
+	if manifest, err = tree.LoadCheckpoint(path, fillFactor); err != nil || manifest.Segment == "" {
+		return
+	}
+
+	// The segment in the manifest has to belong to the tree file.
+	suffix, ok := strings.CutPrefix(manifest.Segment, filepath.Base(treePath)+".wal.")
+	segment, parseErr := strconv.ParseUint(suffix, 10, 64)
+	if !ok || parseErr != nil {
+		err = fmt.Errorf("%w: the segment %q does not belong to %s", ErrCorruptCheckpoint, manifest.Segment, treePath)
+		return
+	}
+	var order byteOrder
+	switch manifest.ByteOrder {
+	case binary.LittleEndian.String():
+		order = binary.LittleEndian
+	case binary.BigEndian.String():
+		order = binary.BigEndian
+	default:
+		err = fmt.Errorf("%w: the byte order %q", ErrCorruptCheckpoint, manifest.ByteOrder)
+		return
+	}
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// The records are decoded the same way as in the tree file.
+	if err = tree.codec.check(); err != nil {
+		return
+	}
+	store := &bpFile[K, V]{codec: tree.codec.inOrder(order), header: fileHeader{order: order}}
+
+	// Replay the segments one after the other, until the next one does not exist.
+	for offset := manifest.Offset; ; segment, offset = segment+1, 0 {
+		file, openErr := os.Open(segmentPath(treePath, segment))
+		if errors.Is(openErr, os.ErrNotExist) {
+			return
+		}
+		if openErr != nil {
+			err = openErr
+			return
+		}
+		_, err = store.replayFrom(tree, file, offset)
+		_ = file.Close()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// dumpStore returns the encoding of the items with the codecs of the tree.
+// A dump is written in little endian whatever the file of the tree uses, and so are Int64Codec and AnyCodec,
+// so a tree of any byte order reads it. The caller holds the lock.
+func (tree *BpTreeG[K, V]) dumpStore() (store *bpFile[K, V], err error) {
+	if err = tree.codec.check(); err != nil {
+		return
+	}
+	store = &bpFile[K, V]{codec: tree.codec.inOrder(binary.LittleEndian), header: fileHeader{order: binary.LittleEndian}}
+	return
+}
+
+// dump streams the unmasked items through the data node links into the file at path, and syncs it.
+// The caller holds the lock.
+func (f *bpFile[K, V]) dump(tree *BpTreeG[K, V], path string) (items uint64, size int64, checksum uint32, err error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermission)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	// Every byte goes to the file and to the checksum. (同时计算校验码)
+	hash := crc32.New(crcTable)
+	writer := bufio.NewWriterSize(io.MultiWriter(file, hash), checkpointBufferSize)
+	write := func(buf []byte) {
+		if err == nil {
+			_, err = writer.Write(buf)
+			size += int64(len(buf))
+		}
+	}
+
+	// The header, then the items.
+	order := f.header.order
+	buf := append(append(make([]byte, 0, checkpointHeaderSize), checkpointMagic[:]...), orderLittle, formatVersion)
+	if order == binary.BigEndian {
+		buf[8] = orderBig
+	}
+	write(buf)
+	// The evicted data nodes are read from their pages without filling the buffer pool.
+	for data := tree.root.BpDataHead(); data != nil && err == nil; data = data.Next {
+		for _, item := range data.scan() {
+			if item.Mask {
+				continue
+			}
+			if buf, err = f.appendKey(buf[:0], item.Key); err != nil {
+				return
+			}
+			if buf, err = f.appendVal(buf, item.Val); err != nil {
+				return
+			}
+			write(buf)
+			items++
+		}
+	}
+
+	// The number of items, then the checksum of everything before it.
+	write(order.AppendUint64(nil, items))
+	if err == nil {
+		err = writer.Flush()
+	}
+	checksum = hash.Sum32()
+	write(order.AppendUint32(nil, checksum))
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	// ⚠️ The dump reaches the disk before it is renamed and the manifest points to it.
+	if err == nil {
+		err = file.Sync()
+	}
+	return
+}
+
+// undump reads the items of the dump at path, and checks them against the manifest.
+func (f *bpFile[K, V]) undump(path string, manifest CheckpointManifest) (items []BpItemG[K, V], err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	if info.Size() != manifest.Size || info.Size() < checkpointHeaderSize+checkpointTrailerSize {
+		err = fmt.Errorf("%w: %s has %d bytes instead of %d", ErrCorruptCheckpoint, path, info.Size(), manifest.Size)
+		return
+	}
+
+	// Check the checksum first, nothing is decoded from a broken dump.
+	checksum := crc32.New(crcTable)
+	if _, err = io.CopyN(checksum, file, info.Size()-checksumSize); err != nil {
+		return
+	}
+	tail := make([]byte, checksumSize)
+	if _, err = io.ReadFull(file, tail); err != nil {
+		return
+	}
+	head := make([]byte, checkpointHeaderSize)
+	if _, err = file.ReadAt(head, 0); err != nil {
+		return
+	}
+	switch {
+	case [8]byte(head[:8]) == checkpointMagic && head[8] == orderLittle:
+		f.header.order = binary.LittleEndian
+	case [8]byte(head[:8]) == checkpointMagic && head[8] == orderBig:
+		f.header.order = binary.BigEndian
+	default:
+		err = fmt.Errorf("%w: %s is not a checkpoint", ErrCorruptCheckpoint, path)
+		return
+	}
+	f.codec = f.codec.inOrder(f.header.order)
+	if sum := f.header.order.Uint32(tail); sum != checksum.Sum32() || sum != manifest.Checksum || head[9] != formatVersion {
+		err = fmt.Errorf("%w: the checksum of %s does not match", ErrCorruptCheckpoint, path)
+		return
+	}
+
+	// Decode the items between the header and the trailer.
+	body := make([]byte, info.Size()-checkpointHeaderSize-checkpointTrailerSize)
+	if _, err = file.ReadAt(body, checkpointHeaderSize); err != nil {
+		return
+	}
+	r := &pageReader{buf: body, order: f.header.order}
+	items = make([]BpItemG[K, V], 0, manifest.Items)
+	for len(r.buf) > 0 {
+		var item BpItemG[K, V]
+		if item.Key, err = f.readKey(r); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCorruptCheckpoint, err)
+		}
+		if item.Val, err = f.readVal(r); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCorruptCheckpoint, err)
+		}
+		items = append(items, item)
+	}
+	count := make([]byte, 8)
+	if _, err = file.ReadAt(count, info.Size()-checkpointTrailerSize); err != nil {
+		return
+	}
+	if n := f.header.order.Uint64(count); n != uint64(len(items)) || n != manifest.Items {
+		err = fmt.Errorf("%w: %d items instead of %d", ErrCorruptCheckpoint, len(items), manifest.Items)
+		items = nil
+	}
+	return
+}
+
+// readManifest reads the manifest of the checkpoint at path,
+// it returns ErrCorruptCheckpoint when the manifest names a dump outside its own directory.
+func readManifest(path string) (manifest CheckpointManifest, err error) {
+	raw, err := os.ReadFile(path + manifestSuffix)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(raw, &manifest); err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorruptCheckpoint, err)
+		return
+	}
+	if name := manifest.Dump; name != filepath.Base(name) || name == "." || name == ".." {
+		manifest, err = CheckpointManifest{}, fmt.Errorf("%w: the dump %q is not a file name", ErrCorruptCheckpoint, name)
+	}
+	return
+}
+
+// writeManifest writes the manifest beside its final name, syncs it and renames it.
+func writeManifest(path string, manifest CheckpointManifest) (err error) {
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermission)
+	if err != nil {
+		return
+	}
+	if _, err = file.Write(raw); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	return renameSynced(path+".tmp", path)
+}
+
+// renameSynced renames the file and syncs its directory, so that the new name survives a crash.
+func renameSynced(from, to string) (err error) {
+	if err = os.Rename(from, to); err != nil {
+		return
+	}
+	dir, err := os.Open(filepath.Dir(to))
+	if err != nil {
+		return
+	}
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
@@ -0,0 +1,275 @@
+package bpTree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/panhongrainbow/go-algorithm/utilhub"
+)
+
+// ➡️ codec operation
+
+// Codec turns keys or values of type T into bytes and back.
+// The pages, the write-ahead log and the checkpoints store the keys and the values with the codecs of the tree.
+// (序列化键值，持久化、导出与复制都靠它)
+type Codec[T any] interface {
+	// Marshal appends the bytes of the value to dst.
+	Marshal(dst []byte, val T) ([]byte, error)
+	// Unmarshal reads a value from the bytes Marshal appended, the value must not refer to src.
+	Unmarshal(src []byte) (T, error)
+	// Size estimates the bytes Marshal appends, for comparing the sizes of values without storing them.
+	Size(val T) int
+}
+
+// WithKeyCodec sets the codec of the keys, its type must match the keys of the tree,
+// otherwise Open and Checkpoint return ErrValueType. Without it, int64, string and []byte keys get Int64Codec, StringCodec and BytesCodec.
+func WithKeyCodec[K any](codec Codec[K]) BpOption {
+	return func(opts *bpOptions) {
+		opts.keyCodec = codec
+	}
+}
+
+// WithValueCodec sets the codec of the values, its type must match the values of the tree,
+// otherwise Open and Checkpoint return ErrValueType. Without it, the values of BpTree get AnyCodec, and string and []byte values get StringCodec and BytesCodec.
+func WithValueCodec[V any](codec Codec[V]) BpOption {
+	return func(opts *bpOptions) {
+		opts.valCodec = codec
+	}
+}
+
+// treeCodec holds the codecs of the keys and the values, nil when the type has none. (键值的编码器)
+type treeCodec[K, V any] struct {
+	keys Codec[K]
+	vals Codec[V]
+	err  error // A codec of another type was given, check returns it.
+}
+
+// newTreeCodec takes the codecs of the options, or the built-in ones in the byte order.
+// A codec of another type is kept as an error, a tree in memory never uses its codecs,
+// so only the methods that store the items report it. (型别不符时记下错误，到储存时才回报)
+func newTreeCodec[K, V any](options bpOptions, order binary.ByteOrder) (codec treeCodec[K, V]) {
+	var ok bool
+	if codec.keys, ok = pickCodec[K](options.keyCodec, order); !ok {
+		codec.err = fmt.Errorf("%w: the key codec %T is not a Codec[%T]", ErrValueType, options.keyCodec, *new(K))
+	} else if codec.vals, ok = pickCodec[V](options.valCodec, order); !ok {
+		codec.err = fmt.Errorf("%w: the value codec %T is not a Codec[%T]", ErrValueType, options.valCodec, *new(V))
+	}
+	return
+}
+
+// pickCodec returns the given codec, or the built-in codec of the type when none is given.
+// It reports false only when the given codec has another type.
+func pickCodec[T any](given any, order binary.ByteOrder) (codec Codec[T], ok bool) {
+	if given != nil {
+		codec, ok = given.(Codec[T])
+		return
+	}
+	for _, builtIn := range []any{Int64Codec{Order: order}, StringCodec{}, BytesCodec{}, AnyCodec{Order: order}} {
+		if codec, ok = builtIn.(Codec[T]); ok {
+			return
+		}
+	}
+	return nil, true
+}
+
+// check returns ErrValueType when the keys or the values have no codec, or a codec of another type.
+func (codec treeCodec[K, V]) check() error {
+	switch {
+	case codec.err != nil:
+		return codec.err
+	case codec.keys == nil:
+		return fmt.Errorf("%w: no codec for the keys of %T, see WithKeyCodec", ErrValueType, *new(K))
+	case codec.vals == nil:
+		return fmt.Errorf("%w: no codec for the values of %T, see WithValueCodec", ErrValueType, *new(V))
+	}
+	return nil
+}
+
+// inOrder returns the codecs with the built-in ones that depend on a byte order switched to the order.
+func (codec treeCodec[K, V]) inOrder(order binary.ByteOrder) treeCodec[K, V] {
+	codec.keys, codec.vals = reorder(codec.keys, order), reorder(codec.vals, order)
+	return codec
+}
+
+// reorder switches Int64Codec and AnyCodec to the order, and returns any other codec as it is.
+func reorder[T any](codec Codec[T], order binary.ByteOrder) Codec[T] {
+	switch any(codec).(type) {
+	case Int64Codec:
+		return any(Int64Codec{Order: order}).(Codec[T])
+	case AnyCodec:
+		return any(AnyCodec{Order: order}).(Codec[T])
+	}
+	return codec
+}
+
+// BytesCodec stores []byte as it is.
+type BytesCodec struct{}
+
+// Marshal appends the bytes.
+func (BytesCodec) Marshal(dst []byte, val []byte) ([]byte, error) {
+	return append(dst, val...), nil
+}
+
+// Unmarshal copies the bytes.
+func (BytesCodec) Unmarshal(src []byte) ([]byte, error) {
+	return bytes.Clone(src), nil
+}
+
+// Size is the length of the bytes.
+func (BytesCodec) Size(val []byte) int {
+	return len(val)
+}
+
+// StringCodec stores the bytes of a string.
+type StringCodec struct{}
+
+// Marshal appends the bytes of the string.
+func (StringCodec) Marshal(dst []byte, val string) ([]byte, error) {
+	return append(dst, val...), nil
+}
+
+// Unmarshal copies the bytes into a string.
+func (StringCodec) Unmarshal(src []byte) (string, error) {
+	return string(src), nil
+}
+
+// Size is the length of the string.
+func (StringCodec) Size(val string) int {
+	return len(val)
+}
+
+// Int64Codec stores an int64 in 8 bytes with the endian helpers of utilhub, binary.LittleEndian when Order is nil.
+type Int64Codec struct {
+	Order binary.ByteOrder
+}
+
+// Marshal appends the 8 bytes of the number.
+func (codec Int64Codec) Marshal(dst []byte, val int64) ([]byte, error) {
+	raw, err := utilhub.Int64SliceToBytes([]int64{val}, codec.order())
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, raw...), nil
+}
+
+// Unmarshal reads the number from exactly 8 bytes.
+func (codec Int64Codec) Unmarshal(src []byte) (int64, error) {
+	if len(src) != 8 {
+		return 0, fmt.Errorf("%w: an int64 of %d bytes", ErrCorruptPage, len(src))
+	}
+	vals, err := utilhub.BytesToInt64Slice(src, codec.order())
+	if err != nil {
+		return 0, err
+	}
+	return vals[0], nil
+}
+
+// Size is always 8.
+func (Int64Codec) Size(int64) int {
+	return 8
+}
+
+// order returns the byte order, binary.LittleEndian by default.
+func (codec Int64Codec) order() binary.ByteOrder {
+	if codec.Order == nil {
+		return binary.LittleEndian
+	}
+	return codec.Order
+}
+
+// AnyCodec stores the values of BpTree with a tag of their type, the same as the pages always did.
+// Only nil, int64, int, float64, bool, string and []byte are stored, any other type returns ErrValueType.
+// Order is binary.LittleEndian or binary.BigEndian, binary.LittleEndian when it is nil.
+type AnyCodec struct {
+	Order binary.ByteOrder
+}
+
+// Marshal appends the tag and the value.
+func (codec AnyCodec) Marshal(dst []byte, val any) ([]byte, error) {
+	return appendAny(dst, codec.order(), val)
+}
+
+// Unmarshal reads a value appended by Marshal.
+func (codec AnyCodec) Unmarshal(src []byte) (any, error) {
+	return readAny(src, codec.order())
+}
+
+// Size is the tag and the bytes of the value, or only the tag for a type that is not stored.
+func (AnyCodec) Size(val any) int {
+	switch v := val.(type) {
+	case int64, int, float64:
+		return 9
+	case bool:
+		return 2
+	case string:
+		return 1 + len(v)
+	case []byte:
+		return 1 + len(v)
+	default:
+		return 1
+	}
+}
+
+// order returns the byte order, binary.LittleEndian unless it is binary.BigEndian.
+func (codec AnyCodec) order() byteOrder {
+	if codec.Order == binary.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// JSONCodec stores the values in JSON with encoding/json.
+type JSONCodec[T any] struct{}
+
+// Marshal appends the JSON of the value.
+func (JSONCodec[T]) Marshal(dst []byte, val T) ([]byte, error) {
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValueType, err)
+	}
+	return append(dst, raw...), nil
+}
+
+// Unmarshal decodes the JSON.
+func (JSONCodec[T]) Unmarshal(src []byte) (val T, err error) {
+	if err = json.Unmarshal(src, &val); err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorruptPage, err)
+	}
+	return
+}
+
+// Size encodes the value to measure it, JSON has no cheaper estimate.
+func (codec JSONCodec[T]) Size(val T) int {
+	raw, _ := codec.Marshal(nil, val)
+	return len(raw)
+}
+
+// GobCodec stores the values with encoding/gob. Every value carries its own type description,
+// so it decodes alone, at the price of a few dozen bytes.
+type GobCodec[T any] struct{}
+
+// Marshal appends the gob of the value.
+func (GobCodec[T]) Marshal(dst []byte, val T) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	if err := gob.NewEncoder(buf).Encode(&val); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValueType, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the gob.
+func (GobCodec[T]) Unmarshal(src []byte) (val T, err error) {
+	if err = gob.NewDecoder(bytes.NewReader(src)).Decode(&val); err != nil {
+		err = fmt.Errorf("%w: %w", ErrCorruptPage, err)
+	}
+	return
+}
+
+// Size encodes the value to measure it, the type description makes any guess too far off.
+func (codec GobCodec[T]) Size(val T) int {
+	raw, _ := codec.Marshal(nil, val)
+	return len(raw)
+}
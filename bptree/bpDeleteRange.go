@@ -0,0 +1,229 @@
+package bpTree
+
+import (
+	"slices"
+	"sort"
+)
+
+// ➡️ range deletion operation
+
+// DeleteRange ensures thread safety, removes every item with from <= key < to, release lock.
+// The subtrees and data nodes that lie inside the range are dropped as a whole, only the data nodes on the two ends
+// of the range lose part of their items; on the way back up, the nodes the range leaves too small are rebalanced.
+// (整段移除范围内的节点，只修剪两端，往上时重新平衡)
+// It returns the number of removed items. When the write-ahead log fails, it returns the error and removes nothing.
+func (tree *BpTreeG[K, V]) DeleteRange(from, to K) (deleted int, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Log the deletion before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walDeleteRange, key: from, to: to}); err != nil {
+		return
+	}
+
+	// Performing the deletion.
+	deleted = tree.deleteRange(from, to)
+
+	// Performing a return.
+	return
+}
+
+// deleteRange removes every item with from <= key < to, the caller holds the lock.
+func (tree *BpTreeG[K, V]) deleteRange(from, to K) (deleted int) {
+	// An empty range.
+	if tree.cfg.compare(from, to) >= 0 {
+		return
+	}
+
+	// Nothing to remove when no item is in the range.
+	first, ix := tree.root.searchBpData(tree.cfg, from).lowerBound(tree.cfg, from)
+	if first == nil || tree.cfg.compare(first.items()[ix].Key, to) >= 0 {
+		return
+	}
+
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	// The data nodes before and after the range are linked to each other before anything is dropped,
+	// so the merging afterwards only sees the data nodes that stay. They are cloned first in copy-on-write mode,
+	// and the trimming finds the same copies. (先连接范围前后的资料节点)
+	left := first
+	if ix == 0 {
+		left = first.Previous
+	}
+	right, _ := tree.root.searchBpData(tree.cfg, to).lowerBound(tree.cfg, to)
+	if left != right {
+		if left != nil {
+			left = tree.ownPath(left.items()[len(left.items())-1].Key, left)
+		}
+		if right != nil {
+			right = tree.ownPath(right.items()[0].Key, right)
+			right.Previous = left
+		}
+		if left != nil {
+			left.Next = right
+		}
+	}
+
+	// Drop and trim the nodes in the range.
+	tree.ownRoot()
+	before := tree.root.count
+	tree.root.deleteRange(tree.cfg, tree.gen, from, to, nil, nil)
+
+	// Nothing left, start over with an empty root, the same as a new tree.
+	if tree.root.children() == 0 {
+		tree.root = &BpIndexG[K, V]{DataNodes: []*BpDataG[K, V]{{gen: tree.gen}}, gen: tree.gen}
+	}
+	deleted = before - tree.root.count
+
+	// Shrink the root while it is too small.
+	tree.shrink()
+
+	// Performing a return.
+	return
+}
+
+// PopMin ensures thread safety, removes and returns the unmasked item with the smallest key, release lock.
+// With duplicates, it takes the oldest item of the key, the same one RemoveOldest removes.
+// Together with PopMax, the tree works as a double-ended priority queue. (双端优先队列)
+func (tree *BpTreeG[K, V]) PopMin() (item BpItemG[K, V], found bool, err error) {
+	return tree.pop(false)
+}
+
+// PopMax ensures thread safety, removes and returns the unmasked item with the largest key, release lock.
+// With duplicates, it takes the newest item of the key, which is the last item in tree order.
+func (tree *BpTreeG[K, V]) PopMax() (item BpItemG[K, V], found bool, err error) {
+	return tree.pop(true)
+}
+
+// pop removes the first or the last unmasked item, the same way as the other deletions.
+// In the log, the first item is the oldest duplicate of its key and the last item is the newest one,
+// the same items RemoveOldest and RemoveValue remove.
+func (tree *BpTreeG[K, V]) pop(last bool) (item BpItemG[K, V], found bool, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Find the item, an empty tree has nothing to pop.
+	data, ix := tree.root.BpDataHead().forward(0)
+	rec := walRecord[K, V]{kind: walRemoveNth}
+	if last {
+		tail := tree.root.BpDataTail()
+		data, ix = tail.backward(len(tail.items()) - 1)
+		rec.kind = walRemove
+	}
+	if data == nil {
+		return
+	}
+
+	// Log the deletion before applying it.
+	rec.key = data.items()[ix].Key
+	if wait, err = tree.file.logRecord(rec); err != nil {
+		return
+	}
+
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	// Remove the item and rebalance along its path.
+	item, found = tree.delFromRoot(data, ix), true
+
+	// Performing a return.
+	return
+}
+
+// ownPath clones the nodes on the path down to the data node, which holds the key,
+// and returns the data node the tree owns from now on. The caller holds the lock.
+func (tree *BpTreeG[K, V]) ownPath(key K, data *BpDataG[K, V]) *BpDataG[K, V] {
+	path, _ := tree.root.pathTo(tree.cfg, key, data)
+	tree.ownRoot()
+	inode := tree.root
+	for _, i := range path[:len(path)-1] {
+		inode = inode.ownIndex(tree.gen, i)
+	}
+	return inode.ownData(tree.gen, path[len(path)-1])
+}
+
+// deleteRange drops the children inside the range and trims the ones that overlap it, the caller owns the node.
+// All keys of the node lie within [lower, upper], and a nil bound means there is no limit on that side,
+// the same as Validate. The children that hold no node or no item any more are dropped,
+// the index keys are taken from the edge values again, and the children left too small are joined with a neighbor.
+func (inode *BpIndexG[K, V]) deleteRange(cfg *bpConfig[K], gen uint64, from, to K, lower, upper *K) {
+	if len(inode.IndexNodes) == 0 {
+		// The bottom index node drops the data nodes inside the range and trims the others.
+		dataNodes := make([]*BpDataG[K, V], 0, len(inode.DataNodes))
+		for i, data := range inode.DataNodes {
+			items := data.items()
+			begin := sort.Search(len(items), func(i int) bool {
+				return cfg.compare(items[i].Key, from) >= 0
+			})
+			end := sort.Search(len(items), func(i int) bool {
+				return cfg.compare(items[i].Key, to) >= 0
+			})
+			if begin == 0 && end == len(items) {
+				continue
+			}
+			if begin < end {
+				// Neighbors may share the backing array after borrowing, so the trimming makes a new one.
+				data = inode.ownData(gen, i)
+				data.Items = append(slices.Clip(data.Items[:begin]), data.Items[end:]...)
+			}
+			dataNodes = append(dataNodes, data)
+		}
+		inode.DataNodes = dataNodes
+		inode.Index = make([]K, 0, len(dataNodes))
+		for _, data := range dataNodes[min(1, len(dataNodes)):] {
+			inode.Index = append(inode.Index, data.items()[0].Key)
+		}
+	} else {
+		// The index nodes drop the children inside the range and descend into the ones that overlap it.
+		indexNodes := make([]*BpIndexG[K, V], 0, len(inode.IndexNodes))
+		for i, child := range inode.IndexNodes {
+			low, up := lower, upper
+			if i > 0 {
+				low = &inode.Index[i-1]
+			}
+			if i < len(inode.Index) {
+				up = &inode.Index[i]
+			}
+
+			switch {
+			case (up != nil && cfg.compare(*up, from) < 0) || (low != nil && cfg.compare(*low, to) >= 0):
+				// Outside the range, the child is kept as it is.
+			case low != nil && up != nil && cfg.compare(*low, from) >= 0 && cfg.compare(*up, to) < 0:
+				// Inside the range, the whole child is dropped. (整个子树移除)
+				continue
+			default:
+				// ⚠️ A child is dropped when it holds no node any more, never by its count,
+				// which leaves out the masked items. (依实际节点判断，不看数量)
+				child = inode.ownIndex(gen, i)
+				if child.deleteRange(cfg, gen, from, to, low, up); child.children() == 0 {
+					continue
+				}
+			}
+			indexNodes = append(indexNodes, child)
+		}
+		inode.IndexNodes = indexNodes
+		inode.Index = make([]K, 0, len(indexNodes))
+		for _, child := range indexNodes[min(1, len(indexNodes)):] {
+			inode.Index = append(inode.Index, child.edgeValue())
+		}
+	}
+
+	// Join the children the range leaves too small, then renew the item count.
+	inode.fixChildren(cfg, gen)
+	inode.sumCount(cfg)
+}
@@ -0,0 +1,152 @@
+package bpTree
+
+import (
+	"iter"
+	"reflect"
+	"slices"
+)
+
+// ➡️ duplicate deletion operation
+
+// The duplicates of a key are kept in insertion order, a new one is always put after the existing ones.
+// So the leftmost duplicate is the oldest, and the rightmost one is the newest. (相同的值按插入顺序排列)
+
+// RemoveOldest ensures thread safety, removes the oldest unmasked item of the key, release lock.
+func (tree *BpTreeG[K, V]) RemoveOldest(key K) (item BpItemG[K, V], removed bool, err error) {
+	return tree.removeDuplicate(key, func(vals iter.Seq[V]) (skip int) {
+		for range vals {
+			return 0
+		}
+		return -1
+	})
+}
+
+// RemoveNewest ensures thread safety, removes the newest unmasked item of the key, release lock.
+func (tree *BpTreeG[K, V]) RemoveNewest(key K) (item BpItemG[K, V], removed bool, err error) {
+	return tree.removeDuplicate(key, func(vals iter.Seq[V]) (skip int) {
+		skip = -1
+		for range vals {
+			skip++
+		}
+		return
+	})
+}
+
+// RemoveMatch ensures thread safety, removes the oldest unmasked item of the key whose value matches, release lock.
+// The match function is called once for every duplicate up to the first match, with the lock held,
+// so it must not call back into the tree.
+func (tree *BpTreeG[K, V]) RemoveMatch(key K, match func(val V) bool) (item BpItemG[K, V], removed bool, err error) {
+	return tree.removeDuplicate(key, func(vals iter.Seq[V]) (skip int) {
+		for val := range vals {
+			if match(val) {
+				return
+			}
+			skip++
+		}
+		return -1
+	})
+}
+
+// RemoveIf ensures thread safety, removes every unmasked item of the key whose value matches, release lock.
+// The match function is called once for every duplicate, with the lock held, so it must not call back into the tree.
+// It returns the number of removed items. When the write-ahead log fails, it stops there and returns the error,
+// the items removed before stay removed.
+func (tree *BpTreeG[K, V]) RemoveIf(key K, match func(val V) bool) (removed int, err error) {
+	// With a write-ahead log, wait for the last record after the lock is released, the ones before it are written first.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Choose the duplicates before anything is changed.
+	var skips []int
+	skip := 0
+	for data, ix := range tree.root.duplicates(tree.cfg, key) {
+		if match(data.items()[ix].Val) {
+			skips = append(skips, skip)
+		}
+		skip++
+	}
+	if len(skips) == 0 {
+		return
+	}
+
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	// Remove from the newest one, so that the positions of the older ones stay the same.
+	// The log holds the positions, the match function is not called again on replay.
+	for _, skip = range slices.Backward(skips) {
+		if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walRemoveNth, key: key, skip: skip}); err != nil {
+			return
+		}
+		if _, ok := tree.deleteToLeft(key, skip); ok {
+			removed++
+		}
+	}
+
+	// Performing a return.
+	return
+}
+
+// RemoveExact ensures thread safety, removes the oldest unmasked item of the key whose value equals val, release lock.
+// The values are compared with ==, a value whose dynamic type is not comparable, such as a slice or a map, equals nothing.
+// As a secondary index, the key is the indexed column and the value the row ID. (当作二级索引使用)
+func (tree *BpTreeG[K, V]) RemoveExact(key K, val V) (item BpItemG[K, V], removed bool, err error) {
+	return tree.RemoveMatch(key, func(other V) bool {
+		return equalValue(other, val)
+	})
+}
+
+// removeDuplicate removes one of the duplicates of the key, choose looks at their values from the oldest one
+// and returns how many of them to skip, or -1 to remove none.
+func (tree *BpTreeG[K, V]) removeDuplicate(key K, choose func(vals iter.Seq[V]) int) (item BpItemG[K, V], removed bool, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Choose the duplicate before anything is changed.
+	skip := choose(func(yield func(V) bool) {
+		for data, ix := range tree.root.duplicates(tree.cfg, key) {
+			if !yield(data.items()[ix].Val) {
+				return
+			}
+		}
+	})
+	if skip < 0 {
+		return
+	}
+
+	// Log the position of the duplicate before removing it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walRemoveNth, key: key, skip: skip}); err != nil {
+		return
+	}
+
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	// Performing deletion operation.
+	item, removed = tree.deleteToLeft(key, skip)
+
+	// Performing a return.
+	return
+}
+
+// equalValue compares two values with ==, without panicking on the dynamic types that are not comparable.
+func equalValue[V any](a, b V) bool {
+	x, y := any(a), any(b)
+	if value := reflect.ValueOf(x); value.IsValid() && !value.Comparable() {
+		return false
+	}
+	return x == y
+}
@@ -0,0 +1,46 @@
+package bpTree
+
+import (
+	"iter"
+	"slices"
+)
+
+// nth finds the unmasked duplicate of the key after skipping the first skip ones.
+func (inode *BpIndexG[K, V]) nth(cfg *bpConfig[K], key K, skip int) (data *BpDataG[K, V], ix int, found bool) {
+	for data, ix = range inode.duplicates(cfg, key) {
+		if skip == 0 {
+			found = true
+			return
+		}
+		skip--
+	}
+	return
+}
+
+// duplicates visits the positions of the unmasked items of the key from the leftmost one.
+func (inode *BpIndexG[K, V]) duplicates(cfg *bpConfig[K], key K) iter.Seq2[*BpDataG[K, V], int] {
+	return func(yield func(*BpDataG[K, V], int) bool) {
+		data, ix, found := inode.locate(cfg, key)
+		for ; found; data, ix = data.forward(ix + 1) {
+			if data == nil || cfg.compare(data.items()[ix].Key, key) != 0 || !yield(data, ix) {
+				return
+			}
+		}
+	}
+}
+
+// pathTo finds the child positions from this node down to the data node, which holds the key.
+// Only the children that may hold the key are searched, the same as addCount.
+func (inode *BpIndexG[K, V]) pathTo(cfg *bpConfig[K], key K, data *BpDataG[K, V]) (path []int, found bool) {
+	if len(inode.IndexNodes) == 0 {
+		ix := slices.Index(inode.DataNodes, data)
+		return []int{ix}, ix >= 0
+	}
+	first, last := inode.childrenOf(cfg, key)
+	for i := first; i <= last; i++ {
+		if path, found = inode.IndexNodes[i].pathTo(cfg, key, data); found {
+			return append([]int{i}, path...), true
+		}
+	}
+	return
+}
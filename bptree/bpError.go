@@ -0,0 +1,90 @@
+package bpTree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ➡️ errors
+
+// ErrIndexCorrupted is reported when the index of a node does not match its child nodes.
+// Use errors.Is to check for it, and errors.As with *IndexCorruptedError to get the offending index.
+var ErrIndexCorrupted = errors.New("the index of B plus tree is corrupted")
+
+// ErrDuplicateKey is returned by Insert when the tree is created with WithUniqueKeys and the key already exists.
+var ErrDuplicateKey = errors.New("the key already exists in B plus tree")
+
+// ErrCopyOnWriteOff is returned by Snapshot when the tree is not created with WithCopyOnWrite.
+var ErrCopyOnWriteOff = errors.New("B plus tree is not in copy-on-write mode")
+
+// ErrNotSorted is returned by BulkLoad when the items are not in ascending order.
+var ErrNotSorted = errors.New("the items for B plus tree are not sorted")
+
+// ErrInvalidFillFactor is returned by BulkLoad when the fill factor is not in (0, 1].
+var ErrInvalidFillFactor = errors.New("the fill factor must be greater than 0 and at most 1")
+
+// ErrNotPersistent is returned by Flush and Close when the tree is not opened by Open.
+var ErrNotPersistent = errors.New("B plus tree is not opened from a file")
+
+// ErrCorruptPage is returned by Open when a page of the file is torn or does not hold what it should.
+var ErrCorruptPage = errors.New("a page of B plus tree file is corrupted")
+
+// ErrPageOverflow is returned by Flush when a node does not fit in a page, see WithPageSize.
+var ErrPageOverflow = errors.New("the node does not fit in a page")
+
+// ErrValueType is returned by Flush when the file cannot store the type of a value.
+var ErrValueType = errors.New("the type of the value cannot be stored")
+
+// ErrCorruptCheckpoint is returned by LoadCheckpoint when the dump does not match its manifest.
+var ErrCorruptCheckpoint = errors.New("the checkpoint of B plus tree is corrupted")
+
+// IndexCorruptedError records the index slice that does not match its child nodes.
+// (记录出错的索引切片)
+type IndexCorruptedError[K any] struct {
+	Index  []K    // A copy of the offending index slice.
+	Reason string // What is wrong with the index.
+}
+
+// Error describes the corrupted index.
+func (e *IndexCorruptedError[K]) Error() string {
+	return fmt.Sprintf("%s: %s, %v", ErrIndexCorrupted, e.Reason, e.Index)
+}
+
+// Unwrap makes errors.Is(err, ErrIndexCorrupted) report true.
+func (e *IndexCorruptedError[K]) Unwrap() error {
+	return ErrIndexCorrupted
+}
+
+// indexCorrupted creates an IndexCorruptedError with a copy of the index,
+// so later changes of the node do not change the error.
+func indexCorrupted[K any](index []K, reason string) error {
+	return &IndexCorruptedError[K]{
+		Index:  append([]K{}, index...),
+		Reason: reason,
+	}
+}
+
+// ErrInvalidStructure is reported by Validate when the tree breaks one of its structural rules.
+var ErrInvalidStructure = errors.New("the structure of B plus tree is invalid")
+
+// StructureError records the path to the first node that breaks a structural rule.
+// (记录出错节点的路径)
+type StructureError struct {
+	Path   string // The path from the root, for example root.IndexNodes[1].DataNodes[0].
+	Reason string // Which rule is broken.
+}
+
+// Error describes the violation and where it is.
+func (e *StructureError) Error() string {
+	return fmt.Sprintf("%s: %s at %s", ErrInvalidStructure, e.Reason, e.Path)
+}
+
+// Unwrap makes errors.Is(err, ErrInvalidStructure) report true.
+func (e *StructureError) Unwrap() error {
+	return ErrInvalidStructure
+}
+
+// structureError creates a StructureError.
+func structureError(path, reason string) error {
+	return &StructureError{Path: path, Reason: reason}
+}
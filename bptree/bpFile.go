@@ -0,0 +1,638 @@
+package bpTree
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+// ➡️ file operation
+
+// filePermission is the permission of a new file.
+const filePermission = 0644
+
+// WithByteOrder chooses the byte order of a new file, binary.LittleEndian or binary.BigEndian.
+// The default is binary.LittleEndian, and an existing file keeps the byte order it was created with.
+func WithByteOrder(order binary.ByteOrder) BpOption {
+	return func(opts *bpOptions) {
+		opts.order = order
+	}
+}
+
+// WithPageSize chooses the page size of a new file, 4096 bytes by default and at least 1024 bytes.
+// Every node takes one page and there are no overflow pages, so the page has to hold a full node of the width
+// with its keys and values, otherwise Flush returns ErrPageOverflow. A single key or value therefore has to stay
+// well below the page size. An existing file keeps the page size it was created with.
+func WithPageSize(size int) BpOption {
+	return func(opts *bpOptions) {
+		opts.pageSize = size
+	}
+}
+
+// bpFile keeps B plus tree in a file of fixed-size pages.
+// Flush writes only the nodes that changed since the last Flush, into pages the last version does not use,
+// and then switches the header. A crash before the header is written leaves the last version as it was.
+// (只写入变动的节点，最后才切换文件头)
+type bpFile[K, V any] struct {
+	file   *os.File
+	pool   *bpPool[K, V] // The buffer pool of the data nodes in the file.
+	log    *bpLog        // The write-ahead log, nil without WithWriteAheadLog.
+	codec  treeCodec[K, V]
+	header fileHeader // The header of the last version.
+	pages  uint32     // The number of pages, including the ones written after the last version.
+	free   []uint32   // The pages the last version does not use, Flush takes the pages for the changed nodes from here.
+}
+
+// Open opens B plus tree in the file at path, or creates the file with an empty tree when it does not exist.
+// A new file takes the width and the options; an existing file keeps the width, the key mode,
+// the byte order and the page size it was created with.
+// Open always adds WithCopyOnWrite to the options, so that Flush can tell the changed nodes from the ones in the file,
+// and Snapshot works on the tree as well. (一律使用写时复制)
+// Every node is stored in one page, there are no overflow pages. A node that does not fit, because of long keys
+// or values, makes Flush return ErrPageOverflow, see WithPageSize.
+// Call Flush to write the changes and Close to release the file. (持久化，重启后还在)
+func Open(path string, width int, opts ...BpOption) (tree *BpTree, err error) {
+	return OpenG[int64, any](path, width, opts...)
+}
+
+// OpenG is Open for keys ordered by the < operator and values of any type.
+// The keys and the values are stored with the codecs of WithKeyCodec and WithValueCodec, or the built-in ones,
+// and ErrValueType is returned when a type has neither.
+func OpenG[K cmp.Ordered, V any](path string, width int, opts ...BpOption) (tree *BpTreeG[K, V], err error) {
+	return openFile[K, V](path, width, cmp.Compare[K], opts...)
+}
+
+// openFile opens or creates the file for a tree of any key and value types.
+func openFile[K, V any](path string, width int, compare func(a, b K) int, opts ...BpOption) (tree *BpTreeG[K, V], err error) {
+	// Apply the options.
+	var options bpOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var order byteOrder = binary.LittleEndian
+	switch options.order {
+	case nil, binary.LittleEndian:
+	case binary.BigEndian:
+		order = binary.BigEndian
+	default:
+		err = fmt.Errorf("unsupported byte order: %s", options.order)
+		return
+	}
+	options.pageSize = max(cmp.Or(options.pageSize, defaultPageSize), minPageSize)
+
+	// Both the keys and the values need a codec.
+	if err = newTreeCodec[K, V](options, order).check(); err != nil {
+		return
+	}
+
+	// Open the file, a new one is created.
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, filePermission)
+	if err != nil {
+		return
+	}
+	store := &bpFile[K, V]{file: file}
+	defer func() {
+		if err != nil {
+			tree = nil
+			_ = file.Close()
+		}
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	// The tree always works in copy-on-write mode, see Open.
+	opts = slices.Concat(opts, []BpOption{WithCopyOnWrite()})
+
+	// A new file starts with an empty tree, which is written at once.
+	if info.Size() == 0 {
+		tree = NewBpTreeFunc[K, V](width, compare, opts...)
+		store.codec = tree.codec
+		store.header = fileHeader{order: order, pageSize: uint32(options.pageSize), pages: 1}
+		store.pages = 1
+		store.attach(tree, options.poolPages)
+		if err = removeSegments(path, 1, 0); err != nil {
+			return
+		}
+		if err = store.flush(tree); err != nil {
+			return
+		}
+		err = store.openLog(tree, options.wal, options.syncInterval)
+		return
+	}
+
+	// An existing file decides the width, the key mode and the byte order of the built-in codecs.
+	if err = store.readHeader(); err != nil {
+		return
+	}
+	opts = append(opts, WithByteOrder(store.header.order))
+	if store.header.flags&headerUnique != 0 {
+		opts = append(opts, WithUniqueKeys())
+	} else {
+		opts = append(opts, WithDuplicates())
+	}
+	if store.header.flags&headerLazy != 0 {
+		opts = append(opts, WithLazyDeletion())
+	}
+	tree = NewBpTreeFunc[K, V](int(store.header.width), compare, opts...)
+	store.codec = tree.codec
+	store.attach(tree, options.poolPages)
+	if err = store.load(tree); err != nil {
+		return
+	}
+
+	// Replay the changes logged since the version in the file, the replay may fault nodes in.
+	err = store.openLog(tree, options.wal, options.syncInterval)
+	store.pool.evict()
+
+	// Performing a return.
+	return
+}
+
+// attach makes the file the one of the tree, with a buffer pool of the pages, and lets the tree lock evict.
+func (f *bpFile[K, V]) attach(tree *BpTreeG[K, V], poolPages int) {
+	f.pool = newPool(f, tree.cfg, poolPages)
+	tree.file, tree.mutex.pool = f, f.pool
+}
+
+// Flush ensures thread safety, writes the nodes changed since the last Flush to the file, release lock.
+// It returns ErrNotPersistent when the tree is not opened by Open.
+func (tree *BpTreeG[K, V]) Flush() (err error) {
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Only a tree opened by Open has a file.
+	if tree.file == nil {
+		err = ErrNotPersistent
+		return
+	}
+
+	// Performing the flushing.
+	err = tree.file.flush(tree)
+
+	// Performing a return.
+	return
+}
+
+// Close ensures thread safety, flushes the tree and closes its file, release lock.
+// The tree keeps working in memory afterward.
+func (tree *BpTreeG[K, V]) Close() (err error) {
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Only a tree opened by Open has a file.
+	if tree.file == nil {
+		err = ErrNotPersistent
+		return
+	}
+
+	// Flush first, the files are closed even when the flushing fails.
+	// The evicted nodes are faulted in before the file is closed.
+	err = tree.file.flush(tree)
+	if poolErr := tree.file.pool.detach(); err == nil {
+		err = poolErr
+	}
+	if tree.file.log != nil {
+		if closeErr := tree.file.log.close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := tree.file.file.Close(); err == nil {
+		err = closeErr
+	}
+	tree.file, tree.mutex.pool = nil, nil
+
+	// Performing a return.
+	return
+}
+
+// flush writes the changed nodes, the free-list and then the header, the caller holds the lock.
+func (f *bpFile[K, V]) flush(tree *BpTreeG[K, V]) (err error) {
+	// Nothing changed since the last version.
+	if tree.root.page != 0 && tree.root.page == f.header.root {
+		return
+	}
+
+	// The written nodes belong to the file now, the next modification clones them, the same as after Snapshot.
+	// It holds even when the flushing fails halfway, the nodes written so far keep their pages.
+	defer func() {
+		tree.gen++
+	}()
+
+	// Write the changed nodes from the bottom up, a parent refers to the pages of its children.
+	root, err := f.writeIndex(tree.root)
+	if err != nil {
+		return
+	}
+
+	// The pages no node of the new version uses are free.
+	used := make([]bool, f.pages)
+	used[0] = true
+	tree.root.eachPage(func(page uint32) {
+		used[page] = true
+	})
+
+	// The data nodes out of the tree leave the buffer pool before their pages are reused.
+	f.pool.release(used)
+	freeCount := func() (count int) {
+		for _, inUse := range used {
+			if !inUse {
+				count++
+			}
+		}
+		return
+	}
+
+	// The free-list takes pages as well, which leaves fewer free pages to list.
+	// Its own pages belong to the new version, so they are marked as used.
+	perPage := (int(f.header.pageSize) - pageHeaderSize - 4 - checksumSize) / 4
+	var list []uint32
+	for count := freeCount(); (count+perPage-1)/perPage > len(list); count = freeCount() {
+		page := f.alloc()
+		list = append(list, page)
+		used = append(used, make([]bool, int(f.pages)-len(used))...)
+		used[page] = true
+	}
+	var free []uint32
+	for page, inUse := range used {
+		if !inUse {
+			free = append(free, uint32(page))
+		}
+	}
+
+	// Write the free-list, every page points to the next one.
+	for i, page := range list {
+		chunk := free[i*perPage : min((i+1)*perPage, len(free))]
+		next := uint32(0)
+		if i+1 < len(list) {
+			next = list[i+1]
+		}
+		buf := f.header.order.AppendUint32(newPage(int(f.header.pageSize), f.header.order, pageFree, len(chunk)), next)
+		for _, id := range chunk {
+			buf = f.header.order.AppendUint32(buf, id)
+		}
+		if err = f.writePage(page, buf); err != nil {
+			return
+		}
+	}
+
+	// ⚠️ The header is written after everything it points to has reached the disk.
+	if err = f.file.Sync(); err != nil {
+		return
+	}
+	header := f.header
+	header.seq++
+	header.width = uint32(tree.cfg.width)
+	header.flags = 0
+	if tree.cfg.unique {
+		header.flags |= headerUnique
+	}
+	if tree.cfg.lazy {
+		header.flags |= headerLazy
+	}
+	header.root, header.pages, header.freeHead, header.freeCount = root, f.pages, 0, uint32(len(free))
+	header.logFrom = 0
+	if f.log != nil {
+		header.logFrom = f.log.kept()
+	}
+	if len(list) > 0 {
+		header.freeHead = list[0]
+	}
+	if _, err = f.file.WriteAt(header.encode(), int64(header.seq%2)*headerSlotSize); err != nil {
+		return
+	}
+	if err = f.file.Sync(); err != nil {
+		return
+	}
+	f.header, f.free = header, free
+
+	// The logged changes are part of the new version, the log starts over in a new segment.
+	if f.log != nil {
+		if err = f.log.rotate(header.seq); err != nil {
+			return
+		}
+	}
+	err = removeSegments(f.file.Name(), cmp.Or(header.logFrom, header.seq), header.seq)
+
+	// Performing a return.
+	return
+}
+
+// alloc takes a page for a changed node, a free page of the last version or a new page at the end.
+func (f *bpFile[K, V]) alloc() (page uint32) {
+	if n := len(f.free); n > 0 {
+		page, f.free = f.free[n-1], f.free[:n-1]
+		return
+	}
+	page = f.pages
+	f.pages++
+	return
+}
+
+// writeIndex writes the index node and its changed children, and returns its page.
+func (f *bpFile[K, V]) writeIndex(inode *BpIndexG[K, V]) (page uint32, err error) {
+	// A node with a page has not changed since it was written.
+	if inode.page != 0 {
+		return inode.page, nil
+	}
+
+	// Write the children first.
+	kind, pages := pageIndex, make([]uint32, 0, len(inode.IndexNodes)+len(inode.DataNodes))
+	for _, child := range inode.IndexNodes {
+		if page, err = f.writeIndex(child); err != nil {
+			return
+		}
+		pages = append(pages, page)
+	}
+	if len(inode.IndexNodes) == 0 {
+		kind = pageBottom
+		for _, data := range inode.DataNodes {
+			if page, err = f.writeData(data); err != nil {
+				return
+			}
+			pages = append(pages, page)
+		}
+	}
+
+	// The pages of the children come first, then the index keys.
+	order := f.header.order
+	buf := newPage(int(f.header.pageSize), order, kind, len(pages))
+	for _, page := range pages {
+		buf = order.AppendUint32(buf, page)
+	}
+	for _, key := range inode.Index {
+		if buf, err = f.appendKey(buf, key); err != nil {
+			return
+		}
+	}
+	page = f.alloc()
+	if err = f.writePage(page, buf); err != nil {
+		return
+	}
+	inode.page = page
+
+	// Performing a return.
+	return
+}
+
+// writeData writes the data node, and returns its page.
+func (f *bpFile[K, V]) writeData(data *BpDataG[K, V]) (page uint32, err error) {
+	// A node with a page has not changed since it was written.
+	if data.page != 0 {
+		return data.page, nil
+	}
+
+	// Every item takes a flag byte, the key, and the value after its length.
+	buf := newPage(int(f.header.pageSize), f.header.order, pageData, len(data.Items))
+	for _, item := range data.Items {
+		flag := byte(0)
+		if item.Mask {
+			flag = 1
+		}
+		if buf, err = f.appendKey(append(buf, flag), item.Key); err != nil {
+			return
+		}
+		if buf, err = f.appendVal(buf, item.Val); err != nil {
+			return
+		}
+	}
+	page = f.alloc()
+	if err = f.writePage(page, buf); err != nil {
+		return
+	}
+	data.page = page
+
+	// The node is written back, the buffer pool may evict it from now on.
+	f.pool.add(data, true)
+
+	// Performing a return.
+	return
+}
+
+// appendKey appends the key after its length.
+func (f *bpFile[K, V]) appendKey(buf []byte, key K) (_ []byte, err error) {
+	start := len(buf)
+	if buf, err = f.codec.keys.Marshal(f.header.order.AppendUint16(buf, 0), key); err != nil {
+		return
+	}
+	if len(buf)-start-2 > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: a key of %d bytes", ErrPageOverflow, len(buf)-start-2)
+	}
+	f.header.order.PutUint16(buf[start:], uint16(len(buf)-start-2))
+	return buf, nil
+}
+
+// appendVal appends the value after its length.
+func (f *bpFile[K, V]) appendVal(buf []byte, val V) (_ []byte, err error) {
+	start := len(buf)
+	if buf, err = f.codec.vals.Marshal(f.header.order.AppendUint32(buf, 0), val); err != nil {
+		return
+	}
+	f.header.order.PutUint32(buf[start:], uint32(len(buf)-start-4))
+	return buf, nil
+}
+
+// writePage seals the page and writes it at its place.
+func (f *bpFile[K, V]) writePage(page uint32, buf []byte) (err error) {
+	if buf, err = sealPage(buf, int(f.header.pageSize), f.header.order); err != nil {
+		return
+	}
+	_, err = f.file.WriteAt(buf, int64(page)*int64(f.header.pageSize))
+	return
+}
+
+// readPage reads the page and checks its checksum.
+func (f *bpFile[K, V]) readPage(page uint32) (r *pageReader, kind byte, n int, err error) {
+	if page == 0 || page >= f.pages {
+		err = fmt.Errorf("%w: page %d is out of the file", ErrCorruptPage, page)
+		return
+	}
+	buf := make([]byte, f.header.pageSize)
+	if _, err = f.file.ReadAt(buf, int64(page)*int64(f.header.pageSize)); err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("%w: page %d is cut off", ErrCorruptPage, page)
+		}
+		return
+	}
+	return openPage(buf, f.header.order, page)
+}
+
+// readHeader takes the newer of the two header slots that are intact.
+func (f *bpFile[K, V]) readHeader() (err error) {
+	buf := make([]byte, 2*headerSlotSize)
+	if _, err = f.file.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return
+	}
+	first, ok1 := decodeHeader(buf[:headerSlotSize])
+	second, ok2 := decodeHeader(buf[headerSlotSize:])
+	switch {
+	case ok1 && (!ok2 || first.seq > second.seq):
+		f.header = first
+	case ok2:
+		f.header = second
+	default:
+		return fmt.Errorf("%w: no intact header in %s", ErrCorruptPage, f.file.Name())
+	}
+	f.pages = f.header.pages
+	return nil
+}
+
+// load reads the free-list and the nodes of the version in the header.
+func (f *bpFile[K, V]) load(tree *BpTreeG[K, V]) (err error) {
+	// Read the free-list.
+	for page := f.header.freeHead; page != 0; {
+		r, kind, n, err := f.readPage(page)
+		if err != nil {
+			return err
+		}
+		if kind != pageFree {
+			return fmt.Errorf("%w: page %d is not a part of the free-list", ErrCorruptPage, page)
+		}
+		page = r.uint32()
+		for ; n > 0; n-- {
+			f.free = append(f.free, r.uint32())
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+	if len(f.free) != int(f.header.freeCount) {
+		return fmt.Errorf("%w: the free-list has %d pages instead of %d", ErrCorruptPage, len(f.free), f.header.freeCount)
+	}
+
+	// Read the nodes from the root, then link the data nodes in order.
+	var dataNodes []*BpDataG[K, V]
+	if tree.root, err = f.readIndex(f.header.root, &dataNodes); err != nil {
+		return
+	}
+	for i := 1; i < len(dataNodes); i++ {
+		dataNodes[i-1].Next, dataNodes[i].Previous = dataNodes[i], dataNodes[i-1]
+	}
+	tree.root.recountAll(tree.cfg)
+
+	// The loaded nodes belong to the file, the next modification clones them.
+	tree.gen++
+
+	// Performing a return.
+	return
+}
+
+// readIndex reads the index node at the page and its children, the data nodes are collected in order.
+func (f *bpFile[K, V]) readIndex(page uint32, dataNodes *[]*BpDataG[K, V]) (inode *BpIndexG[K, V], err error) {
+	r, kind, n, err := f.readPage(page)
+	if err != nil {
+		return
+	}
+	if (kind != pageIndex && kind != pageBottom) || n == 0 {
+		err = fmt.Errorf("%w: page %d is not an index node", ErrCorruptPage, page)
+		return
+	}
+
+	// The pages of the children, then the index keys.
+	pages := make([]uint32, n)
+	for i := range pages {
+		pages[i] = r.uint32()
+	}
+	inode = &BpIndexG[K, V]{Index: make([]K, n-1), page: page}
+	for i := range inode.Index {
+		if inode.Index[i], err = f.readKey(r); err != nil {
+			return
+		}
+	}
+
+	// Read the children.
+	for _, child := range pages {
+		if kind == pageIndex {
+			var sub *BpIndexG[K, V]
+			if sub, err = f.readIndex(child, dataNodes); err != nil {
+				return
+			}
+			inode.IndexNodes = append(inode.IndexNodes, sub)
+			continue
+		}
+		var data *BpDataG[K, V]
+		if data, err = f.readData(child); err != nil {
+			return
+		}
+		inode.DataNodes = append(inode.DataNodes, data)
+		*dataNodes = append(*dataNodes, data)
+
+		// Every page is checked once, the buffer pool keeps the nodes up to its capacity.
+		f.pool.add(data, false)
+		f.pool.evict()
+	}
+
+	// Performing a return.
+	return
+}
+
+// readData reads the data node at the page.
+func (f *bpFile[K, V]) readData(page uint32) (data *BpDataG[K, V], err error) {
+	r, kind, n, err := f.readPage(page)
+	if err != nil {
+		return
+	}
+	return f.decodeData(page, r, kind, n)
+}
+
+// decodeData decodes the data node in the page, it only uses the codecs of the file.
+func (f *bpFile[K, V]) decodeData(page uint32, r *pageReader, kind byte, n int) (data *BpDataG[K, V], err error) {
+	if kind != pageData {
+		err = fmt.Errorf("%w: page %d is not a data node", ErrCorruptPage, page)
+		return
+	}
+	data = &BpDataG[K, V]{Items: make([]BpItemG[K, V], n), page: page}
+	for i := range data.Items {
+		item := &data.Items[i]
+		item.Mask = r.uint8() == 1
+		if item.Key, err = f.readKey(r); err != nil {
+			return
+		}
+		if item.Val, err = f.readVal(r); err != nil {
+			return
+		}
+	}
+
+	// Performing a return.
+	return
+}
+
+// readKey reads a key after its length.
+func (f *bpFile[K, V]) readKey(r *pageReader) (key K, err error) {
+	raw := r.next(int(r.uint16()))
+	if r.err != nil {
+		return key, r.err
+	}
+	return f.codec.keys.Unmarshal(raw)
+}
+
+// readVal reads a value after its length.
+func (f *bpFile[K, V]) readVal(r *pageReader) (val V, err error) {
+	raw := r.next(int(r.uint32()))
+	if r.err != nil {
+		return val, r.err
+	}
+	return f.codec.vals.Unmarshal(raw)
+}
+
+// eachPage visits the pages of every node in the subtree.
+func (inode *BpIndexG[K, V]) eachPage(visit func(page uint32)) {
+	visit(inode.page)
+	for _, child := range inode.IndexNodes {
+		child.eachPage(visit)
+	}
+	for _, data := range inode.DataNodes {
+		visit(data.page)
+	}
+}
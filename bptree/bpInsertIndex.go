@@ -1,7 +1,6 @@
 package bpTree
 
 import (
-	"fmt"
 	"sort"
 )
 
@@ -29,18 +28,24 @@ const (
 	statusDeleteProtrude
 )
 
-// BpIndex is the index of the B plus tree.
-type BpIndex struct {
-	Index      []int64    // The maximum values of each group of BpData
-	IndexNodes []*BpIndex // Index nodes
-	DataNodes  []*BpData  // Data nodes
+// BpIndexG is the index of the B plus tree.
+type BpIndexG[K, V any] struct {
+	Index      []K               // The maximum values of each group of BpData
+	IndexNodes []*BpIndexG[K, V] // Index nodes
+	DataNodes  []*BpDataG[K, V]  // Data nodes
+	count      int               // The number of items in the subtree.
+	gen        uint64            // The copy-on-write generation that owns the node.
+	page       uint32            // The page that holds the node in the file, zero until the node is flushed.
 }
 
+// BpIndexG with int64 keys.
+type BpIndex = BpIndexG[int64, any]
+
 // insertBpDataValue inserts a new index into the BpIndex.
 // 经由 BpIndex 直接在新增
-func (inode *BpIndex) insertItem(newNode *BpIndex, item BpItem) (popIx int, popKey int64, popNode *BpIndex, status int, err error) {
-	var newIndex int64
-	var sideDataNode *BpData
+func (inode *BpIndexG[K, V]) insertItem(cfg *bpConfig[K], newNode *BpIndexG[K, V], item BpItemG[K, V]) (popIx int, popKey K, popNode *BpIndexG[K, V], status int, err error) {
+	var newIndex K
+	var sideDataNode *BpDataG[K, V]
 	status = statusNormal // status is used to inform the root node that it is not the root node here, so the state becomes Normal !.
 	// 状态是用来告知 root 节点，在这里不是 root 节点，所以状态变为 Normal !
 
@@ -52,43 +57,55 @@ func (inode *BpIndex) insertItem(newNode *BpIndex, item BpItem) (popIx int, popK
 
 		// Use binary search to find the index(i) where the key should be inserted.
 		ix := sort.Search(len(inode.Index), func(i int) bool {
-			if inode.Index[i] == item.Key {
-				return inode.Index[i] > item.Key
-			}
-
-			return inode.Index[i] >= item.Key
+			return cfg.compare(inode.Index[i], item.Key) > 0 // No equal sign, equal keys go to the right. ‼️
 		})
 
 		// >>>>> >>>>> >>>>> 进入递归
 
 		if len(inode.IndexNodes) > 0 {
 			if len(inode.IndexNodes) != (len(inode.Index) + 1) {
-				err = fmt.Errorf("the number of indexes is incorrect, %v", inode.Index)
+				err = indexCorrupted(inode.Index, "the number of indexes is incorrect")
+				return
+			}
+			if len(inode.DataNodes) > 0 {
+				err = indexCorrupted(inode.Index, "both IndexNodes and DataNodes have data, we cannot determine the type of node")
 				return
 			}
 
 			// If there are index nodes, recursively insert the item into the appropriate node.
 			// (这里有递回去找到接近资料切片的地方)
-			popIx, popKey, popNode, status, err = inode.IndexNodes[ix].insertItem(nil, item)
+			popIx, popKey, popNode, status, err = inode.IndexNodes[ix].insertItem(cfg, nil, item)
 
-			status = statusProtrudeInode
-			if popKey != 0 {
-				err = inode.mergeUpgradedKeyNode(ix, popKey, popNode)
-				popKey = 0
+			// Only the checks on the way down return errors, before anything is modified, so a failed insertion leaves the tree unchanged.
+			// The splits on the way back up only check shapes that the checks on the way down already guarantee.
+			// (只有往下找时的检查会出错，此时树还没有被修改；往上分裂时检查的形状，往下时都已经确认过)
+			if err != nil {
+				return
+			}
+
+			// The status tells whether a key has been popped out, because any key, zero included, is a valid key.
+			// (任何值都可能是合法的 key，所以用状态判断是否有弹出的 key)
+			if status == statusProtrudeDnode {
+				err = inode.mergeUpgradedKeyNode(cfg, ix, popKey, popNode)
 				popNode = nil
 			}
+			status = statusProtrudeInode
 
-			if popNode != nil && popKey == 0 {
+			if popNode != nil {
 				// New index node has been created independently and are going to be upgraded and overwrite inode.
-				inode.ackUpgradeIndexNode(ix, popNode) // 在这里同意并覆写 inode
+				inode.ackUpgradeIndexNode(cfg, ix, popNode) // 在这里同意并覆写 inode
 				popNode = nil
 			}
 
-			if len(inode.Index) >= BpWidth && len(inode.Index)%2 != 0 { // 进行 pop 和奇数
-				popNode, err = inode.protrudeInOddBpWidth()
+			if len(inode.Index) >= cfg.width && len(inode.Index)%2 != 0 { // 进行 pop 和奇数
+				if popNode, err = inode.protrudeInOddBpWidth(); err == nil {
+					popNode.IndexNodes[1].trace(cfg, TraceSplit)
+				}
 				return
-			} else if len(inode.Index) >= BpWidth && len(inode.Index)%2 == 0 { // 进行 pop 和奇数
-				popNode, err = inode.protrudeInEvenBpWidth()
+			} else if len(inode.Index) >= cfg.width && len(inode.Index)%2 == 0 { // 进行 pop 和奇数
+				if popNode, err = inode.protrudeInEvenBpWidth(); err == nil {
+					popNode.IndexNodes[1].trace(cfg, TraceSplit)
+				}
 				return
 			}
 
@@ -98,38 +115,44 @@ func (inode *BpIndex) insertItem(newNode *BpIndex, item BpItem) (popIx int, popK
 		// If there are data nodes, insert the new item at the determined index.
 		if len(inode.DataNodes) > 0 {
 			if len(inode.DataNodes) != (len(inode.Index) + 1) {
-				err = fmt.Errorf("the number of indexes is incorrect, %v", inode.Index)
+				err = indexCorrupted(inode.Index, "the number of indexes is incorrect")
 				return
 			}
 
 			// >>>>> 进入第 1 个资料结点入口
 
-			inode.DataNodes[ix].insert(item) // Insert item at index ix.
+			inode.DataNodes[ix].insert(cfg, item) // Insert item at index ix.
 
-			if len(inode.DataNodes[ix].Items) >= BpWidth {
-				sideDataNode, err = inode.DataNodes[ix].split()
+			if len(inode.DataNodes[ix].Items) >= cfg.width {
+				sideDataNode, err = inode.DataNodes[ix].split(cfg)
 				if err != nil {
 					return
 				}
+				sideDataNode.trace(cfg, TraceSplit)
 
-				inode.DataNodes = append(inode.DataNodes, &BpData{})
+				inode.DataNodes = append(inode.DataNodes, &BpDataG[K, V]{})
 				copy(inode.DataNodes[(ix+1)+1:], inode.DataNodes[(ix+1):])
 				inode.DataNodes[ix+1] = sideDataNode
 
-				inode.insertBpIX(sideDataNode.Items[0].Key)
+				inode.insertBpIX(cfg, sideDataNode.Items[0].Key)
 			}
 
-			if len(inode.Index) >= BpWidth {
-				popKey, popNode, err = inode.splitWithDnode()
+			if len(inode.Index) >= cfg.width {
+				popKey, popNode, err = inode.splitWithDnode(cfg)
 				status = statusProtrudeDnode
 				popIx = ix
 				if err != nil {
 					return
 				}
+				popNode.trace(cfg, TraceSplit)
 			}
 
 			return
 		}
+
+		// An index without any child node has nowhere to put the item. (有索引却没有子节点)
+		err = indexCorrupted(inode.Index, "the index has no child nodes")
+		return
 	}
 
 	// >>>>> 进入第 2 个资料结点入口
@@ -139,16 +162,17 @@ func (inode *BpIndex) insertItem(newNode *BpIndex, item BpItem) (popIx int, popK
 	if newNode == nil && len(inode.Index) == 0 {
 		if len(inode.DataNodes) != 1 {
 			// 资料大于1，就会有索引，就不会进入这里
-			err = fmt.Errorf("the number of indexes is incorrect initially")
+			err = indexCorrupted(inode.Index, "the number of indexes is incorrect initially")
 			return
 		}
-		inode.DataNodes[0].insert(item) // >>>>> (add to DataNodes)
+		inode.DataNodes[0].insert(cfg, item) // >>>>> (add to DataNodes)
 
-		if inode.DataNodes[0].dataLength() >= BpWidth {
-			sideDataNode, err = inode.DataNodes[0].split() // newIndex
+		if inode.DataNodes[0].dataLength() >= cfg.width {
+			sideDataNode, err = inode.DataNodes[0].split(cfg) // newIndex
 			if err != nil {
 				return
 			}
+			sideDataNode.trace(cfg, TraceSplit)
 
 			inode.DataNodes = append(inode.DataNodes, sideDataNode)
 			newIndex = sideDataNode.Items[0].Key
@@ -156,17 +180,23 @@ func (inode *BpIndex) insertItem(newNode *BpIndex, item BpItem) (popIx int, popK
 	}
 
 	if sideDataNode != nil {
-		inode.insertBpIX(newIndex)
+		inode.insertBpIX(cfg, newIndex)
 
-		if len(inode.Index) >= BpWidth && len(inode.Index)%2 != 0 { // 进行 pop 和奇数 (可能没在使用)
-			var node *BpIndex
-			node, err = inode.protrudeInOddBpWidth()
+		if len(inode.Index) >= cfg.width && len(inode.Index)%2 != 0 { // 进行 pop 和奇数 (可能没在使用)
+			var node *BpIndexG[K, V]
+			if node, err = inode.protrudeInOddBpWidth(); err != nil {
+				return
+			}
 			*inode = *node
+			inode.trace(cfg, TraceRootPromotion)
 			return
-		} else if len(inode.Index) >= BpWidth && len(inode.Index)%2 == 0 { // 进行 pop 和奇数 (可能没在使用)
-			var node *BpIndex
-			node, err = inode.protrudeInEvenBpWidth()
+		} else if len(inode.Index) >= cfg.width && len(inode.Index)%2 == 0 { // 进行 pop 和奇数 (可能没在使用)
+			var node *BpIndexG[K, V]
+			if node, err = inode.protrudeInEvenBpWidth(); err != nil {
+				return
+			}
 			*inode = *node
+			inode.trace(cfg, TraceRootPromotion)
 			return
 		}
 
@@ -183,14 +213,14 @@ func (inode *BpIndex) insertItem(newNode *BpIndex, item BpItem) (popIx int, popK
 // (承认新独立的索引结点)
 //
 //go:inline
-func (inode *BpIndex) ackUpgradeIndexNode(ix int, popNode *BpIndex) {
+func (inode *BpIndexG[K, V]) ackUpgradeIndexNode(cfg *bpConfig[K], ix int, popNode *BpIndexG[K, V]) {
 	// 这个函式不能在 root 节点上使用，
 
 	// Insert popNode.Index[0]
-	inode.insertBpIX(popNode.Index[0])
+	inode.insertBpIX(cfg, popNode.Index[0])
 
 	// Create a new BpIndex
-	node := &BpIndex{}
+	node := &BpIndexG[K, V]{}
 
 	// Split inode.IndexNodes into three parts and then merge them into the new node.IndexNodes
 	node.IndexNodes = append(node.IndexNodes, inode.IndexNodes[:ix]...)   // positions are from 0 to ix.
@@ -214,14 +244,14 @@ func (inode *BpIndex) ackUpgradeIndexNode(ix int, popNode *BpIndex) {
 
 // insertBpIX inserts a new index at the correct position using binary search.
 // Just inserting an index slice won't result in any errors, so it doesn't return an error.
-func (inode *BpIndex) insertBpIX(newIx int64) {
+func (inode *BpIndexG[K, V]) insertBpIX(cfg *bpConfig[K], newIx K) {
 	// Use binary search to find the position where the index should be inserted.
 	ix := sort.Search(len(inode.Index), func(i int) bool {
-		return inode.Index[i] >= newIx
+		return cfg.compare(inode.Index[i], newIx) >= 0
 	})
 
 	// Expand the slice to accommodate the new item.
-	inode.Index = append(inode.Index, 0)
+	inode.Index = append(inode.Index, newIx)
 
 	// Shift the elements to the right to make space for the new item.
 	copy(inode.Index[ix+1:], inode.Index[ix:])
@@ -233,16 +263,16 @@ func (inode *BpIndex) insertBpIX(newIx int64) {
 }
 
 // protrudeInOddBpWidth performs index upgrade; when the middle value of the index slice pops out, it gets upgraded to the upper-level index.
-// This is used when the width of BpWidth is odd.
+// This is used when the width of the tree is odd.
 // (进行索引升级，当索引切片的中间值会弹出升级成上层的索引)
-func (inode *BpIndex) protrudeInOddBpWidth() (middle *BpIndex, err error) {
+func (inode *BpIndexG[K, V]) protrudeInOddBpWidth() (middle *BpIndexG[K, V], err error) {
 	// At the beginning, a check is performed.
-	// This function is designed to handle cases where the BpWidth is an odd number,
+	// This function is designed to handle cases where the width is an odd number,
 	// meaning the length of the Index slice is odd,
 	// and the length of the IndexNodes slice is even,
 	// with a difference of 1 in the lengths.(Index 切片 和 IndexNodes 切片长度 差 1)
 	if len(inode.Index)%2 != 1 || len(inode.IndexNodes)%2 != 0 {
-		err = fmt.Errorf("in the case of an odd width, protruding oversized index nodes results in an error")
+		err = indexCorrupted(inode.Index, "in the case of an odd width, protruding oversized index nodes results in an error")
 		return
 	}
 
@@ -251,23 +281,23 @@ func (inode *BpIndex) protrudeInOddBpWidth() (middle *BpIndex, err error) {
 	indexNodeLen := len(inode.IndexNodes) / 2
 
 	// Create a new left node.
-	leftNode := &BpIndex{
-		Index:      append([]int64{}, inode.Index[:indexLen]...),
-		IndexNodes: append([]*BpIndex{}, inode.IndexNodes[:indexNodeLen]...),
+	leftNode := &BpIndexG[K, V]{
+		Index:      append([]K{}, inode.Index[:indexLen]...),
+		IndexNodes: append([]*BpIndexG[K, V]{}, inode.IndexNodes[:indexNodeLen]...),
 		// DataNode slice is set to nil directly. It should not be used later.
 	}
 
 	// Create a new right node.
-	rightNode := &BpIndex{
-		Index:      append([]int64{}, inode.Index[indexLen+1:]...),
-		IndexNodes: append([]*BpIndex{}, inode.IndexNodes[indexNodeLen:]...),
+	rightNode := &BpIndexG[K, V]{
+		Index:      append([]K{}, inode.Index[indexLen+1:]...),
+		IndexNodes: append([]*BpIndexG[K, V]{}, inode.IndexNodes[indexNodeLen:]...),
 		// DataNode slice is set to nil directly. It should not be used later.
 	}
 
 	// Create a new middle node.
-	middle = &BpIndex{
+	middle = &BpIndexG[K, V]{
 		Index:      inode.Index[indexLen : indexLen+1],
-		IndexNodes: []*BpIndex{leftNode, rightNode},
+		IndexNodes: []*BpIndexG[K, V]{leftNode, rightNode},
 		// DataNode slice is set to nil directly. It should not be used later.
 	}
 
@@ -276,16 +306,16 @@ func (inode *BpIndex) protrudeInOddBpWidth() (middle *BpIndex, err error) {
 }
 
 // protrudeInOddBpWidth performs index upgrade; when the middle value of the index slice pops out, it gets upgraded to the upper-level index.
-// This is used when the width of BpWidth is even.
+// This is used when the width of the tree is even.
 // (进行索引升级，当索引切片的中间值会弹出升级成上层的索引)
-func (inode *BpIndex) protrudeInEvenBpWidth() (popMiddleNode *BpIndex, err error) {
+func (inode *BpIndexG[K, V]) protrudeInEvenBpWidth() (popMiddleNode *BpIndexG[K, V], err error) {
 	// At the beginning, a check is performed.
-	// This function is designed to handle cases where the BpWidth is an odd number,
+	// This function is designed to handle cases where the width is an odd number,
 	// meaning the length of the Index slice is even,
 	// and the length of the IndexNodes slice is odd,
 	// with a difference of 1 in the lengths.(Index 切片 和 IndexNodes 切片长度 差 1)
 	if len(inode.Index)%2 != 0 || len(inode.IndexNodes)%2 != 1 {
-		err = fmt.Errorf("in the case of an odd width, protruding oversized index nodes results in an error")
+		err = indexCorrupted(inode.Index, "in the case of an odd width, protruding oversized index nodes results in an error")
 		return
 	}
 
@@ -294,23 +324,23 @@ func (inode *BpIndex) protrudeInEvenBpWidth() (popMiddleNode *BpIndex, err error
 	indexNodeLen := (len(inode.IndexNodes) - 1) / 2
 
 	// Create a new left node.
-	leftNode := &BpIndex{
-		Index:      append([]int64{}, inode.Index[:indexLen]...),
-		IndexNodes: append([]*BpIndex{}, inode.IndexNodes[:indexNodeLen+1]...),
+	leftNode := &BpIndexG[K, V]{
+		Index:      append([]K{}, inode.Index[:indexLen]...),
+		IndexNodes: append([]*BpIndexG[K, V]{}, inode.IndexNodes[:indexNodeLen+1]...),
 		// DataNode slice is set to nil directly. It should not be used later.
 	}
 
 	// Create a new right node.
-	rightNode := &BpIndex{
-		Index:      append([]int64{}, inode.Index[indexLen+1:]...),
-		IndexNodes: append([]*BpIndex{}, inode.IndexNodes[indexNodeLen+1:]...),
+	rightNode := &BpIndexG[K, V]{
+		Index:      append([]K{}, inode.Index[indexLen+1:]...),
+		IndexNodes: append([]*BpIndexG[K, V]{}, inode.IndexNodes[indexNodeLen+1:]...),
 		// DataNode slice is set to nil directly. It should not be used later.
 	}
 
 	// Create a new middle node.
-	popMiddleNode = &BpIndex{
+	popMiddleNode = &BpIndexG[K, V]{
 		Index:      inode.Index[indexLen : indexLen+1],
-		IndexNodes: []*BpIndex{leftNode, rightNode},
+		IndexNodes: []*BpIndexG[K, V]{leftNode, rightNode},
 		// DataNode slice is set to nil directly. It should not be used later.
 	}
 
@@ -321,49 +351,49 @@ func (inode *BpIndex) protrudeInEvenBpWidth() (popMiddleNode *BpIndex, err error
 // >>>>> >>>>> >>>>> split and merge the bottom-level index node.
 
 // splitWithDnode splits the bottom-level index node effectively and returns a new independent key and index node.
-func (inode *BpIndex) splitWithDnode() (key int64, side *BpIndex, err error) {
+func (inode *BpIndexG[K, V]) splitWithDnode(cfg *bpConfig[K]) (key K, side *BpIndexG[K, V], err error) {
 	// Check if both IndexNodes and DataNodes have data,
 	// which is incorrect as we don't know the type of node.
 	if len(inode.IndexNodes) != 0 && len(inode.DataNodes) != 0 {
-		err = fmt.Errorf("both IndexNodes and DataNodes have data, we cannot determine the type of node")
+		err = indexCorrupted(inode.Index, "both IndexNodes and DataNodes have data, we cannot determine the type of node")
 		return
 	}
 
 	// Handle splitting based on DataNodes.
 	if len(inode.DataNodes) != 0 {
 		// Create a new node named side.
-		side = &BpIndex{}
+		side = &BpIndexG[K, V]{}
 		length := len(inode.DataNodes)
 
 		// Append a portion of the Index and DataNodes to the 'side' structure.
-		side.Index = append(side.Index, inode.Index[(length-BpHalfWidth):]...)
-		// This is equivalent to side.Index = append(side.Index, inode.Index[(length-BpHalfWidth):len(inode.Index)])
-		// 这里等于 side.Index = append(side.Index, inode.Index[(length-BpHalfWidth):len(inode.Index)])
+		side.Index = append(side.Index, inode.Index[(length-cfg.halfWidth):]...)
+		// This is equivalent to side.Index = append(side.Index, inode.Index[(length-cfg.halfWidth):len(inode.Index)])
+		// 这里等于 side.Index = append(side.Index, inode.Index[(length-cfg.halfWidth):len(inode.Index)])
 
-		side.DataNodes = append(side.DataNodes, inode.DataNodes[(length-BpHalfWidth):]...)
-		// This is equivalent to side.DataNodes = append(side.DataNodes, inode.DataNodes[(length-BpHalfWidth):len(inode.DataNodes)]),
+		side.DataNodes = append(side.DataNodes, inode.DataNodes[(length-cfg.halfWidth):]...)
+		// This is equivalent to side.DataNodes = append(side.DataNodes, inode.DataNodes[(length-cfg.halfWidth):len(inode.DataNodes)]),
 		// where len(inode.DataNodes) will be one more than len(inode.Index)
 		// Hence, side.DataNodes will be one more than side.Index, so the slicing operation is correct.
 
-		// 这里等于 side.DataNodes = append(side.DataNodes, inode.DataNodes[(length-BpHalfWidth):len(inode.DataNodes)])，len(inode.DataNodes) 会比 len(inode.Index) 多 1 个
+		// 这里等于 side.DataNodes = append(side.DataNodes, inode.DataNodes[(length-cfg.halfWidth):len(inode.DataNodes)])，len(inode.DataNodes) 会比 len(inode.Index) 多 1 个
 		// 最后 side.DataNodes 会比 side.Index 多 1 个，所以切割操作正确
 
 		// The logic here is a bit complex, where the length is the length of the DataNode slice,
-		// and the expression [(length-BpHalfWidth):] determines how much data the new node should take.
-		// When [(length-BpHalfWidth):] is applied to the index code, side.Index = append(side.Index, inode.Index[(length-BpHalfWidth):]...),
+		// and the expression [(length-cfg.halfWidth):] determines how much data the new node should take.
+		// When [(length-cfg.halfWidth):] is applied to the index code, side.Index = append(side.Index, inode.Index[(length-cfg.halfWidth):]...),
 		// the length will be one less than side.DataNodes. This ensures that DataNodes has one more element than Index,
 		// so the overall logic is correct.
 
-		// 这里的程式码有点复杂，其中长度 length 为 DataNode 切片的长度，那式子 [(length-BpHalfWidth):] 中的 BpHalfWidth 意思就为新节点要取多少笔资料，
-		// 再把 [(length-BpHalfWidth):] 套上 index 的代码中，side.Index = append(side.Index, inode.Index[(length-BpHalfWidth):]...)，长度会比 side.DataNodes 少 1 个
+		// 这里的程式码有点复杂，其中长度 length 为 DataNode 切片的长度，那式子 [(length-cfg.halfWidth):] 中的 cfg.halfWidth 意思就为新节点要取多少笔资料，
+		// 再把 [(length-cfg.halfWidth):] 套上 index 的代码中，side.Index = append(side.Index, inode.Index[(length-cfg.halfWidth):]...)，长度会比 side.DataNodes 少 1 个
 		// 这样就符合 DataNodes 的切片长度比 Index 多 1，整个逻辑是正确的
 
 		// Update the 'key' assignment with a value from the original Index.
-		key = inode.Index[length-BpHalfWidth-1]
+		key = inode.Index[length-cfg.halfWidth-1]
 
 		// Update the original Index and DataNodes by removing the appended portion.
-		inode.Index = inode.Index[0 : length-BpHalfWidth-1]
-		inode.DataNodes = inode.DataNodes[0 : length-BpHalfWidth]
+		inode.Index = inode.Index[0 : length-cfg.halfWidth-1]
+		inode.DataNodes = inode.DataNodes[0 : length-cfg.halfWidth]
 	}
 
 	// Just return and don't worry about anything.
@@ -372,17 +402,17 @@ func (inode *BpIndex) splitWithDnode() (key int64, side *BpIndex, err error) {
 
 // mergeWithDnode combines the newly split index nodes created by splitWithDnode into a new node,
 // overwriting the original inode's address.
-func (inode *BpIndex) mergeWithDnode(podKey int64, side *BpIndex) error {
+func (inode *BpIndexG[K, V]) mergeWithDnode(podKey K, side *BpIndexG[K, V]) error {
 	// Create a new BpIndex structure.
-	originAndSide := &BpIndex{
-		Index: []int64{podKey},
+	originAndSide := &BpIndexG[K, V]{
+		Index: []K{podKey},
 	}
 
 	// Copy the current inode's Index, IndexNodes, and DataNodes to the new structure.
-	copyInode := &BpIndex{
-		Index:      append([]int64{}, inode.Index...),
-		IndexNodes: append([]*BpIndex{}, inode.IndexNodes...),
-		DataNodes:  append([]*BpData{}, inode.DataNodes...),
+	copyInode := &BpIndexG[K, V]{
+		Index:      append([]K{}, inode.Index...),
+		IndexNodes: append([]*BpIndexG[K, V]{}, inode.IndexNodes...),
+		DataNodes:  append([]*BpDataG[K, V]{}, inode.DataNodes...),
 	}
 
 	// Add copyInode to originAndSide.IndexNodes.
@@ -401,15 +431,15 @@ func (inode *BpIndex) mergeWithDnode(podKey int64, side *BpIndex) error {
 // >>>>> >>>>> >>>>> merge the upgraded key and upgraded index node.
 
 // mergeUpgradedKeyNode merges the to-be-upgraded Key and the to-be-upgraded Inode into the parent higher-level index node.
-func (inode *BpIndex) mergeUpgradedKeyNode(insertAfterPosition int, key int64, side *BpIndex) (err error) {
+func (inode *BpIndexG[K, V]) mergeUpgradedKeyNode(cfg *bpConfig[K], insertAfterPosition int, key K, side *BpIndexG[K, V]) (err error) {
 	// The B Plus tree builds an index, and when some indexes become independent, they turn into keys.
 	// Merging these keys into other index nodes is not difficult.
 	// It's just a matter of sorting.
 	insertAfterPosition = insertAfterPosition + 1
-	inode.insertBpIX(key)
+	inode.insertBpIX(cfg, key)
 
 	// Store the upgraded index node named side at the appropriate position in the IndexNodes slice.
-	inode.IndexNodes = append(inode.IndexNodes, &BpIndex{})
+	inode.IndexNodes = append(inode.IndexNodes, &BpIndexG[K, V]{})
 	copy(inode.IndexNodes[insertAfterPosition+1:], inode.IndexNodes[insertAfterPosition:])
 	inode.IndexNodes[insertAfterPosition] = side
 
@@ -0,0 +1,305 @@
+package bpTree
+
+// ➡️ iterator
+
+// BpIterator walks the items of B plus tree in both directions through the links between data nodes.
+// It does not hold the tree lock between calls. Every call takes the read lock, and when the tree has been modified
+// in the meantime, the iterator finds its position again by key. (树被修改后，用 key 重新定位)
+// Several iterators can walk the same tree in parallel, but one iterator must not be shared between goroutines.
+type BpIteratorG[K, V any] struct {
+	tree    *BpTreeG[K, V] // The tree being walked.
+	data    *BpDataG[K, V] // The data node of the current item; nil means there is no current position.
+	ix      int            // The position of the current item in data.Items.
+	item    BpItemG[K, V]  // A copy of the current item.
+	dup     int            // The number of unmasked items with the same key before the current item.
+	valid   bool           // Whether the iterator points to an item.
+	exact   bool           // Whether data and ix still point to the current item after renewing.
+	version uint64         // The tree version when data and ix were last located.
+}
+
+// BpIteratorG with int64 keys.
+type BpIterator = BpIteratorG[int64, any]
+
+// Iterator returns an iterator that is not positioned yet; call Seek, First or Last before reading from it.
+func (tree *BpTreeG[K, V]) Iterator() *BpIteratorG[K, V] {
+	return &BpIteratorG[K, V]{tree: tree}
+}
+
+// Seek moves the iterator to the first unmasked item whose key is not less than the key.
+func (it *BpIteratorG[K, V]) Seek(key K) bool {
+	// Acquire a read lock, lookups and scans run in parallel.
+	it.tree.mutex.RLock()
+	defer it.tree.mutex.RUnlock()
+	return it.seek(key)
+}
+
+// seek is Seek without the lock, the caller holds the read lock.
+func (it *BpIteratorG[K, V]) seek(key K) bool {
+	// Find the first item whose key is not less than the key.
+	data, ix := it.tree.root.searchBpData(it.tree.cfg, key).lowerBound(it.tree.cfg, key)
+	data, ix = data.forward(ix)
+
+	// The item found is always the leftmost one among the same keys.
+	it.locate(data, ix, 0)
+	return it.valid
+}
+
+// First moves the iterator to the smallest unmasked item.
+func (it *BpIteratorG[K, V]) First() bool {
+	// Acquire a read lock, lookups and scans run in parallel.
+	it.tree.mutex.RLock()
+	defer it.tree.mutex.RUnlock()
+	return it.first()
+}
+
+// first is First without the lock, the caller holds the read lock.
+func (it *BpIteratorG[K, V]) first() bool {
+	// Start from the head of the data nodes.
+	data, ix := it.tree.root.BpDataHead().forward(0)
+	it.locate(data, ix, 0)
+	return it.valid
+}
+
+// Last moves the iterator to the largest unmasked item.
+func (it *BpIteratorG[K, V]) Last() bool {
+	// Acquire a read lock, lookups and scans run in parallel.
+	it.tree.mutex.RLock()
+	defer it.tree.mutex.RUnlock()
+	return it.last()
+}
+
+// last is Last without the lock, the caller holds the read lock.
+func (it *BpIteratorG[K, V]) last() bool {
+	// Start from the tail of the data nodes.
+	tail := it.tree.root.BpDataTail()
+	data, ix := tail.backward(len(tail.items()) - 1)
+	it.locate(data, ix, data.countDuplicatesBefore(it.tree.cfg, ix))
+	return it.valid
+}
+
+// Next moves the iterator to the next unmasked item in ascending order.
+func (it *BpIteratorG[K, V]) Next() bool {
+	// Acquire a read lock, lookups and scans run in parallel.
+	it.tree.mutex.RLock()
+	defer it.tree.mutex.RUnlock()
+	return it.next()
+}
+
+// next is Next without the lock, the caller holds the read lock.
+func (it *BpIteratorG[K, V]) next() bool {
+	// An invalid iterator stays invalid until it is positioned again.
+	if !it.valid {
+		return false
+	}
+
+	// Find the position again if the tree has been modified.
+	it.renew()
+
+	// When the current item has been removed, the renewed position already points to its successor.
+	data, ix := it.data, it.ix
+	if it.exact {
+		ix++
+	}
+	data, ix = data.forward(ix)
+
+	// Count the duplicates along the way.
+	dup := 0
+	if data != nil && it.tree.cfg.compare(data.items()[ix].Key, it.item.Key) == 0 {
+		dup = it.dup + 1
+		if !it.exact {
+			dup = it.dup
+		}
+	}
+
+	it.locate(data, ix, dup)
+	return it.valid
+}
+
+// Prev moves the iterator to the previous unmasked item in ascending order.
+func (it *BpIteratorG[K, V]) Prev() bool {
+	// Acquire a read lock, lookups and scans run in parallel.
+	it.tree.mutex.RLock()
+	defer it.tree.mutex.RUnlock()
+	return it.prev()
+}
+
+// prev is Prev without the lock, the caller holds the read lock.
+func (it *BpIteratorG[K, V]) prev() bool {
+	// An invalid iterator stays invalid until it is positioned again.
+	if !it.valid {
+		return false
+	}
+
+	// Find the position again if the tree has been modified.
+	it.renew()
+
+	// The previous item is always before the renewed position.
+	var data *BpDataG[K, V]
+	var ix int
+	if it.data == nil { // Every remaining item is smaller, so start from the tail. (从尾端开始)
+		tail := it.tree.root.BpDataTail()
+		data, ix = tail.backward(len(tail.items()) - 1)
+	} else {
+		data, ix = it.data.backward(it.ix - 1)
+	}
+
+	// Count the duplicates along the way.
+	dup := 0
+	if data != nil {
+		if it.tree.cfg.compare(data.items()[ix].Key, it.item.Key) == 0 && it.dup > 0 {
+			dup = it.dup - 1
+		} else {
+			dup = data.countDuplicatesBefore(it.tree.cfg, ix)
+		}
+	}
+
+	it.locate(data, ix, dup)
+	return it.valid
+}
+
+// Valid reports whether the iterator points to an item.
+func (it *BpIteratorG[K, V]) Valid() bool {
+	return it.valid
+}
+
+// Key returns the key of the current item.
+func (it *BpIteratorG[K, V]) Key() K {
+	return it.item.Key
+}
+
+// Value returns the value of the current item.
+func (it *BpIteratorG[K, V]) Value() V {
+	return it.item.Val
+}
+
+// locate records the new position of the iterator. A nil data node makes the iterator invalid.
+func (it *BpIteratorG[K, V]) locate(data *BpDataG[K, V], ix int, dup int) {
+	it.data, it.ix, it.dup = data, ix, dup
+	it.exact = true
+	it.version = it.tree.version
+	it.valid = data != nil
+	if it.valid {
+		it.item = data.items()[ix]
+	}
+}
+
+// renew finds the current item again after the tree has been modified.
+// If the current item no longer exists, the position moves to its successor and exact becomes false.
+// (目前资料被删除时，位置会移到下一笔)
+func (it *BpIteratorG[K, V]) renew() {
+	// Nothing changed, the position is still correct.
+	if it.version == it.tree.version {
+		return
+	}
+	it.version = it.tree.version
+
+	// Find the leftmost item with the same key, then skip the duplicates counted before.
+	key := it.item.Key
+	data, ix := it.tree.root.searchBpData(it.tree.cfg, key).lowerBound(it.tree.cfg, key)
+	data, ix = data.forward(ix)
+	for count := 0; data != nil && it.tree.cfg.compare(data.items()[ix].Key, key) == 0; count++ {
+		if count == it.dup {
+			it.data, it.ix, it.exact = data, ix, true
+			return
+		}
+		data, ix = data.forward(ix + 1)
+	}
+
+	// The current item is gone; data may become nil when every remaining item is smaller.
+	it.data, it.ix, it.exact = data, ix, false
+}
+
+// ➡️ range scan
+
+// Range returns the unmasked items with from <= key < to in ascending order.
+func (tree *BpTreeG[K, V]) Range(from, to K) (items []BpItemG[K, V]) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Walk forward from the first item not less than from.
+	data, ix := tree.root.searchBpData(tree.cfg, from).lowerBound(tree.cfg, from)
+	for data, ix = data.forward(ix); data != nil && tree.cfg.compare(data.items()[ix].Key, to) < 0; data, ix = data.forward(ix + 1) {
+		items = append(items, data.items()[ix])
+	}
+
+	// Performing a return.
+	return
+}
+
+// ReverseRange returns the unmasked items with from <= key < to in descending order.
+func (tree *BpTreeG[K, V]) ReverseRange(from, to K) (items []BpItemG[K, V]) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Walk backward from the last item less than to.
+	data, ix := tree.root.lastBefore(tree.cfg, to)
+	for ; data != nil && tree.cfg.compare(data.items()[ix].Key, from) >= 0; data, ix = data.backward(ix - 1) {
+		items = append(items, data.items()[ix])
+	}
+
+	// Performing a return.
+	return
+}
+
+// ➡️ position helpers
+
+// lastBefore returns the position of the last unmasked item whose key is less than the key.
+func (inode *BpIndexG[K, V]) lastBefore(cfg *bpConfig[K], key K) (data *BpDataG[K, V], ix int) {
+	// Every item before the lower bound is less than the key.
+	data, ix = inode.searchBpData(cfg, key).lowerBound(cfg, key)
+	if data == nil { // Every item is less than the key, so start from the tail. (从尾端开始)
+		tail := inode.BpDataTail()
+		return tail.backward(len(tail.items()) - 1)
+	}
+	return data.backward(ix - 1)
+}
+
+// forward moves to the first unmasked item at or after position ix, following the Next links.
+// It returns a nil data node when there is no such item.
+func (data *BpDataG[K, V]) forward(ix int) (*BpDataG[K, V], int) {
+	for data != nil {
+		items := data.items()
+		for ; ix < len(items); ix++ {
+			if !items[ix].Mask {
+				return data, ix
+			}
+		}
+		data, ix = data.Next, 0
+	}
+	return nil, 0
+}
+
+// backward moves to the first unmasked item at or before position ix, following the Previous links.
+// It returns a nil data node when there is no such item.
+func (data *BpDataG[K, V]) backward(ix int) (*BpDataG[K, V], int) {
+	for data != nil {
+		items := data.items()
+		for ; ix >= 0; ix-- {
+			if ix < len(items) && !items[ix].Mask {
+				return data, ix
+			}
+		}
+		if data = data.Previous; data != nil {
+			ix = len(data.items()) - 1
+		}
+	}
+	return nil, 0
+}
+
+// countDuplicatesBefore counts the unmasked items before position ix that share its key.
+func (data *BpDataG[K, V]) countDuplicatesBefore(cfg *bpConfig[K], ix int) (count int) {
+	if data == nil {
+		return
+	}
+	key := data.items()[ix].Key
+	for data, ix = data.backward(ix - 1); data != nil && cfg.compare(data.items()[ix].Key, key) == 0; data, ix = data.backward(ix - 1) {
+		count++
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package bpTree
+
+import (
+	"slices"
+)
+
+// ➡️ lazy deletion operation
+
+// compactFillFactor leaves room in the rebuilt nodes, so that the insertions after Compact do not split at once.
+const compactFillFactor = 0.75
+
+// WithLazyDeletion makes the deletions mask the items instead of removing them.
+// A masked item stays in its data node, so a deletion never splits, borrows or merges, and its latency stays flat
+// under heavy churn. Every read skips the masked items, and with unique keys an insertion of the same key
+// uses a masked item again.
+// Compact removes the masked items for good. (删除只做遮罩，Compact 再真正移除)
+func WithLazyDeletion() BpOption {
+	return func(opts *bpOptions) {
+		opts.lazy = true
+	}
+}
+
+// Compact ensures thread safety, removes the masked items and rebuilds the index, release lock.
+// The unmasked items are loaded again from the bottom up, the same way BulkLoad does.
+// It can be called at any time, for example from a background goroutine on a timer.
+// It returns the number of removed items. When the write-ahead log fails, it returns the error and removes nothing.
+func (tree *BpTreeG[K, V]) Compact() (removed int, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Nothing to do without masked items.
+	if removed = tree.root.masked(); removed == 0 {
+		return
+	}
+
+	// Log the compaction before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walCompact}); err != nil {
+		return 0, err
+	}
+
+	// Performing the compaction.
+	tree.compact()
+
+	// Performing a return.
+	return
+}
+
+// compact loads the unmasked items into new nodes, the caller holds the lock.
+// The old nodes may still be shared with snapshots, so none of them is changed.
+func (tree *BpTreeG[K, V]) compact() {
+	tree.load(tree.root.liveItems(tree.cfg), compactFillFactor)
+}
+
+// Masked ensures thread safety, returns the number of masked items waiting for Compact, release lock.
+// It walks through every data node.
+func (tree *BpTreeG[K, V]) Masked() (masked int) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Count the masked items.
+	masked = tree.root.masked()
+
+	// Performing a return.
+	return
+}
+
+// revive puts the item in the place of a masked item with the same key, the caller holds the lock and owns the path.
+// It reports false when the key has no masked item.
+func (tree *BpTreeG[K, V]) revive(item BpItemG[K, V]) bool {
+	data, ix := tree.root.searchBpData(tree.cfg, item.Key).lowerBound(tree.cfg, item.Key)
+	for ; data != nil; data, ix = data.Next, 0 {
+		for items := data.items(); ix < len(items); ix++ {
+			if tree.cfg.compare(items[ix].Key, item.Key) != 0 {
+				return false
+			}
+			if items[ix].Mask {
+				data.Items[ix] = item
+				if !tree.root.addCount(tree.cfg, item.Key, data, 1) {
+					tree.root.recount(tree.cfg, item.Key)
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// masked counts the masked items of the subtree.
+func (inode *BpIndexG[K, V]) masked() (count int) {
+	inode.eachData(func(data *BpDataG[K, V]) {
+		for _, item := range data.scan() {
+			if item.Mask {
+				count++
+			}
+		}
+	})
+	return
+}
+
+// liveItems returns the unmasked items of the subtree in ascending order.
+func (inode *BpIndexG[K, V]) liveItems(cfg *bpConfig[K]) []BpItemG[K, V] {
+	return slices.Collect(inode.walk(cfg, nil, nil))
+}
+
+// live counts the unmasked items of the data node. Only lazy deletion masks items,
+// so the other modes take the length without looking at the items.
+// An evicted data node answers with the count kept in its frame, so counting does not fault it in.
+func (data *BpDataG[K, V]) live(cfg *bpConfig[K]) (count int) {
+	if frame := data.frame; frame != nil && frame.evicted.Load() {
+		return frame.live
+	}
+	if !cfg.lazy {
+		return len(data.Items)
+	}
+	for _, item := range data.Items {
+		if !item.Mask {
+			count++
+		}
+	}
+	return
+}
+
+// addCount adds delta to the item counts along the path down to the data node, which holds the key.
+// Reviving does not move any node, so only this path changes. It reports false when the data node
+// is not found under the children that may hold the key. (只更新到资料节点的路径)
+func (inode *BpIndexG[K, V]) addCount(cfg *bpConfig[K], key K, data *BpDataG[K, V], delta int) (found bool) {
+	if len(inode.IndexNodes) == 0 {
+		found = slices.Contains(inode.DataNodes, data)
+	} else {
+		// The children from first to last may hold the key, the same as recount.
+		first, last := inode.childrenOf(cfg, key)
+		for i := first; i <= last && !found; i++ {
+			found = inode.IndexNodes[i].addCount(cfg, key, data, delta)
+		}
+	}
+	if found {
+		inode.count += delta
+	}
+	return
+}
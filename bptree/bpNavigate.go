@@ -0,0 +1,109 @@
+package bpTree
+
+// ➡️ navigation operation
+
+// Min ensures thread safety, returns the first unmasked item with the smallest key, release lock.
+func (tree *BpTreeG[K, V]) Min() (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Start from the head and skip the masked items.
+	return itemAt(tree.root.BpDataHead().forward(0))
+}
+
+// Max ensures thread safety, returns the last unmasked item with the largest key, release lock.
+func (tree *BpTreeG[K, V]) Max() (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Start from the tail and skip the masked items.
+	tail := tree.root.BpDataTail()
+	return itemAt(tail.backward(len(tail.items()) - 1))
+}
+
+// Ceiling ensures thread safety, returns the first unmasked item whose key is not less than the key, release lock.
+// (大于或等于 key 的最小资料)
+func (tree *BpTreeG[K, V]) Ceiling(key K) (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// The lower bound may be masked, so move forward from it.
+	data, ix := tree.root.searchBpData(tree.cfg, key).lowerBound(tree.cfg, key)
+	return itemAt(data.forward(ix))
+}
+
+// Higher ensures thread safety, returns the first unmasked item whose key is greater than the key, release lock.
+// (大于 key 的最小资料)
+func (tree *BpTreeG[K, V]) Higher(key K) (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Move forward from the first item after the key.
+	data, ix := tree.root.upperBound(tree.cfg, key)
+	return itemAt(data.forward(ix))
+}
+
+// Floor ensures thread safety, returns the last unmasked item whose key is not greater than the key, release lock.
+// With duplicates, it is the last item of the key. (小于或等于 key 的最大资料)
+func (tree *BpTreeG[K, V]) Floor(key K) (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Every item before the upper bound is not greater than the key.
+	data, ix := tree.root.upperBound(tree.cfg, key)
+	if data == nil { // Every item is not greater than the key, so start from the tail. (从尾端开始)
+		tail := tree.root.BpDataTail()
+		return itemAt(tail.backward(len(tail.items()) - 1))
+	}
+	return itemAt(data.backward(ix - 1))
+}
+
+// Lower ensures thread safety, returns the last unmasked item whose key is less than the key, release lock.
+// (小于 key 的最大资料)
+func (tree *BpTreeG[K, V]) Lower(key K) (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Move backward from the last item before the key.
+	return itemAt(tree.root.lastBefore(tree.cfg, key))
+}
+
+// upperBound returns the position of the first item whose key is greater than the key, masked or not.
+// It returns nil when no item is greater than the key.
+func (inode *BpIndexG[K, V]) upperBound(cfg *bpConfig[K], key K) (data *BpDataG[K, V], ix int) {
+	// Skip the duplicates from the lower bound, they may span several data nodes. (跳过相同值)
+	for data, ix = inode.searchBpData(cfg, key).lowerBound(cfg, key); data != nil; data, ix = data.Next, 0 {
+		for items := data.items(); ix < len(items); ix++ {
+			if cfg.compare(items[ix].Key, key) > 0 {
+				return
+			}
+		}
+	}
+	return
+}
+
+// itemAt returns the item at the position from forward or backward, found is false for a nil data node.
+func itemAt[K, V any](data *BpDataG[K, V], ix int) (item BpItemG[K, V], found bool) {
+	if data == nil {
+		return
+	}
+	return data.items()[ix], true
+}
@@ -0,0 +1,261 @@
+package bpTree
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// ➡️ page format
+
+// The file is a sequence of fixed-size pages, and every node of the tree takes one page. (每个节点占一页)
+//
+//	page 0    the header, with one slot at offset 0 and one at headerSlotSize. Flush writes the slots in turn,
+//	          so a torn write of one slot leaves the previous version of the tree in the other one.
+//	page 1..  index nodes, data nodes and the free-list.
+//
+// Every node page starts with its kind and the number of its entries, and ends with a CRC-32C checksum
+// of the bytes before it. The numbers are written in the byte order the file is created with.
+
+const (
+	defaultPageSize = 4096 // The page size of a new file, unless WithPageSize is given.
+	minPageSize     = 1024 // Page 0 holds both header slots.
+	headerSlotSize  = 512  // The distance between the two header slots, one disk sector.
+	headerSize      = 60   // The bytes of a header slot, including its checksum.
+	pageHeaderSize  = 8    // The kind and the number of entries at the start of a node page.
+	checksumSize    = 4    // The checksum at the end of every page and header slot.
+	formatVersion   = 2    // Increased when the layout changes.
+)
+
+// fileMagic starts every header slot.
+var fileMagic = [8]byte{'B', 'P', 'T', 'R', 'E', 'E', 'P', 'G'}
+
+// The kinds of pages.
+const (
+	pageIndex  byte = iota + 1 // An index node whose children are index nodes.
+	pageBottom                 // An index node whose children are data nodes.
+	pageData                   // A data node.
+	pageFree                   // A part of the free-list.
+)
+
+// The byte order flags in the header, they are read before the byte order is known.
+const (
+	orderLittle byte = 'L'
+	orderBig    byte = 'B'
+)
+
+// The mode flags in the header.
+const (
+	headerUnique uint32 = 1 << iota // Created with WithUniqueKeys.
+	headerLazy                      // Created with WithLazyDeletion.
+)
+
+// byteOrder reads and appends the numbers, binary.LittleEndian and binary.BigEndian are both.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// crcTable is the CRC-32C table of the checksums.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// fileHeader is a header slot, it points to the root and to the free-list of one version of the tree.
+type fileHeader struct {
+	order     byteOrder // The byte order of every number in the file.
+	pageSize  uint32    // The size of every page.
+	width     uint32    // The width of the tree.
+	flags     uint32    // headerUnique and headerLazy.
+	seq       uint64    // Increased by every Flush, the slot with the larger one is the newer version.
+	root      uint32    // The page of the root.
+	pages     uint32    // The number of pages in the file.
+	freeHead  uint32    // The first page of the free-list, zero when there is none.
+	freeCount uint32    // The number of free pages.
+	logFrom   uint64    // The first segment of the write-ahead log to keep, the one of the last checkpoint; zero without one.
+}
+
+// encode lays out the header slot and appends its checksum.
+func (h fileHeader) encode() []byte {
+	buf := make([]byte, headerSize)
+	copy(buf, fileMagic[:])
+	buf[8] = orderLittle
+	if h.order == binary.BigEndian {
+		buf[8] = orderBig
+	}
+	buf[9] = formatVersion
+	h.order.PutUint32(buf[12:], h.pageSize)
+	h.order.PutUint32(buf[16:], h.width)
+	h.order.PutUint32(buf[20:], h.flags)
+	h.order.PutUint64(buf[24:], h.seq)
+	h.order.PutUint32(buf[32:], h.root)
+	h.order.PutUint32(buf[36:], h.pages)
+	h.order.PutUint32(buf[40:], h.freeHead)
+	h.order.PutUint32(buf[44:], h.freeCount)
+	h.order.PutUint64(buf[48:], h.logFrom)
+	h.order.PutUint32(buf[56:], crc32.Checksum(buf[:56], crcTable))
+	return buf
+}
+
+// decodeHeader reads a header slot, it reports false when the slot is torn or is not a header at all.
+func decodeHeader(buf []byte) (h fileHeader, ok bool) {
+	if len(buf) < headerSize || [8]byte(buf[:8]) != fileMagic || buf[9] != formatVersion {
+		return
+	}
+	switch buf[8] {
+	case orderLittle:
+		h.order = binary.LittleEndian
+	case orderBig:
+		h.order = binary.BigEndian
+	default:
+		return
+	}
+	if h.order.Uint32(buf[56:]) != crc32.Checksum(buf[:56], crcTable) {
+		return
+	}
+	h.pageSize = h.order.Uint32(buf[12:])
+	h.width = h.order.Uint32(buf[16:])
+	h.flags = h.order.Uint32(buf[20:])
+	h.seq = h.order.Uint64(buf[24:])
+	h.root = h.order.Uint32(buf[32:])
+	h.pages = h.order.Uint32(buf[36:])
+	h.freeHead = h.order.Uint32(buf[40:])
+	h.freeCount = h.order.Uint32(buf[44:])
+	h.logFrom = h.order.Uint64(buf[48:])
+	ok = h.pageSize >= minPageSize && h.width >= 3
+	return
+}
+
+// newPage starts a node page of the kind with n entries.
+func newPage(pageSize int, order byteOrder, kind byte, n int) []byte {
+	buf := make([]byte, pageHeaderSize, pageSize)
+	buf[0] = kind
+	order.PutUint32(buf[4:], uint32(n))
+	return buf
+}
+
+// sealPage pads the page to its size and appends the checksum.
+// It returns ErrPageOverflow when the entries do not fit.
+func sealPage(buf []byte, pageSize int, order byteOrder) ([]byte, error) {
+	if len(buf) > pageSize-checksumSize {
+		return nil, fmt.Errorf("%w: %d bytes in a page of %d", ErrPageOverflow, len(buf)+checksumSize, pageSize)
+	}
+	buf = append(buf, make([]byte, pageSize-checksumSize-len(buf))...)
+	return order.AppendUint32(buf, crc32.Checksum(buf, crcTable)), nil
+}
+
+// pageReader reads the entries of a page in order, the first read past the end sets err. (依序读取)
+type pageReader struct {
+	buf   []byte
+	order binary.ByteOrder
+	err   error
+}
+
+// openPage checks the checksum of the page, and returns a reader positioned after the kind and the count.
+func openPage(buf []byte, order byteOrder, id uint32) (r *pageReader, kind byte, n int, err error) {
+	end := len(buf) - checksumSize
+	if order.Uint32(buf[end:]) != crc32.Checksum(buf[:end], crcTable) {
+		err = fmt.Errorf("%w: the checksum of page %d does not match", ErrCorruptPage, id)
+		return
+	}
+	r = &pageReader{buf: buf[pageHeaderSize:end], order: order}
+	kind, n = buf[0], int(order.Uint32(buf[4:]))
+	return
+}
+
+// next takes the next n bytes.
+func (r *pageReader) next(n int) []byte {
+	if r.err != nil || n > len(r.buf) {
+		if r.err == nil {
+			r.err = fmt.Errorf("%w: an entry runs past the end of the page", ErrCorruptPage)
+		}
+		return make([]byte, min(n, 8))
+	}
+	b := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// uint8 reads one byte.
+func (r *pageReader) uint8() byte {
+	return r.next(1)[0]
+}
+
+// uint16 reads two bytes.
+func (r *pageReader) uint16() uint16 {
+	return r.order.Uint16(r.next(2))
+}
+
+// uint32 reads four bytes.
+func (r *pageReader) uint32() uint32 {
+	return r.order.Uint32(r.next(4))
+}
+
+// uint64 reads eight bytes.
+func (r *pageReader) uint64() uint64 {
+	return r.order.Uint64(r.next(8))
+}
+
+// ➡️ value encoding
+
+// The tags of the value types the page format stores by itself.
+const (
+	valueNil byte = iota
+	valueInt64
+	valueInt
+	valueFloat64
+	valueBool
+	valueString
+	valueBytes
+)
+
+// appendAny appends the value with a tag of its type. Only nil, int64, int, float64, bool, string and []byte
+// are stored, any other type returns ErrValueType. (只保存基本型别)
+func appendAny(dst []byte, order byteOrder, val any) ([]byte, error) {
+	switch v := val.(type) {
+	case nil:
+		return append(dst, valueNil), nil
+	case int64:
+		return order.AppendUint64(append(dst, valueInt64), uint64(v)), nil
+	case int:
+		return order.AppendUint64(append(dst, valueInt), uint64(v)), nil
+	case float64:
+		return order.AppendUint64(append(dst, valueFloat64), math.Float64bits(v)), nil
+	case bool:
+		if v {
+			return append(dst, valueBool, 1), nil
+		}
+		return append(dst, valueBool, 0), nil
+	case string:
+		return append(append(dst, valueString), v...), nil
+	case []byte:
+		return append(append(dst, valueBytes), v...), nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrValueType, val)
+	}
+}
+
+// readAny reads a value appended by appendAny.
+func readAny(src []byte, order byteOrder) (val any, err error) {
+	if len(src) == 0 {
+		return nil, fmt.Errorf("%w: an empty value", ErrCorruptPage)
+	}
+	tag, body := src[0], src[1:]
+	switch {
+	case tag == valueNil && len(body) == 0:
+		return nil, nil
+	case tag == valueInt64 && len(body) == 8:
+		return int64(order.Uint64(body)), nil
+	case tag == valueInt && len(body) == 8:
+		return int(order.Uint64(body)), nil
+	case tag == valueFloat64 && len(body) == 8:
+		return math.Float64frombits(order.Uint64(body)), nil
+	case tag == valueBool && len(body) == 1:
+		return body[0] == 1, nil
+	case tag == valueString:
+		return string(body), nil
+	case tag == valueBytes:
+		return append([]byte{}, body...), nil
+	default:
+		return nil, fmt.Errorf("%w: a value with tag %d and %d bytes", ErrCorruptPage, tag, len(body))
+	}
+}
@@ -0,0 +1,375 @@
+package bpTree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/panhongrainbow/go-algorithm/utilhub"
+)
+
+// ➡️ buffer pool operation
+
+const (
+	poolMemoryShare  = 4    // Without WithBufferPool, the pool takes a quarter of the available memory.
+	minPoolPages     = 16   // The smallest pool.
+	fallbackPoolSize = 1024 // The pool size when the available memory is unknown, for example outside Linux.
+)
+
+// WithBufferPool sets the number of data nodes a tree opened by Open keeps in memory between operations, at least 16.
+// Without it, the pool takes a quarter of the memory utilhub.GetLinuxAvailableMemory reports, one page per node,
+// or 1024 nodes when the available memory is unknown.
+//
+// The buffer pool holds the items of the data nodes that are in the file. The index nodes, and the data nodes
+// changed since the last Flush, always stay in memory; Flush writes the changed ones back and hands them to the pool.
+// A data node whose items were evicted keeps its place in the tree, and an operation that reads it faults
+// the items in from its page. (换出的节点在读取时从文件载入)
+// The CLOCK hand evicts the nodes not read for a while when the tree lock is released: the write lock always,
+// the read lock when nobody waits for the write lock. Nothing is evicted in the middle of an operation,
+// so a split or a merge never loses a node, and a node a snapshot is reading is pinned until it is done.
+// While an operation runs, for example Validate, the pool may hold more nodes than its capacity.
+//
+// Open reads every page once to check it, and keeps the data nodes up to the capacity. ⚠️ A page that cannot be
+// faulted in later, because the file was changed behind the tree or the disk fails, makes the operation panic
+// with an error matching ErrCorruptPage. Close faults every node in, so the tree keeps working in memory afterward.
+func WithBufferPool(pages int) BpOption {
+	return func(opts *bpOptions) {
+		opts.poolPages = pages
+	}
+}
+
+// PoolStats counts the work of the buffer pool.
+type PoolStats struct {
+	Hits       uint64 // The reads of a data node that found its items in memory.
+	Misses     uint64 // The data nodes read from the file, when Open checks them or when they are faulted in.
+	Evictions  uint64 // The data nodes whose items were dropped to stay within the capacity.
+	WriteBacks uint64 // The changed data nodes Flush wrote back and handed to the pool.
+	Resident   int    // The data nodes of the pool with their items in memory now.
+	Capacity   int    // The most data nodes the pool keeps in memory between operations.
+}
+
+// PoolStats ensures thread safety, returns the counters of the buffer pool, release lock.
+// It returns ErrNotPersistent when the tree is not opened by Open.
+func (tree *BpTreeG[K, V]) PoolStats() (stats PoolStats, err error) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Only a tree opened by Open has a pool.
+	if tree.file == nil {
+		err = ErrNotPersistent
+		return
+	}
+	stats = tree.file.pool.stats()
+
+	// Performing a return.
+	return
+}
+
+// bpMutex is the lock of the tree. Releasing it gives the buffer pool the chance to evict,
+// at a point where no operation is in the middle of a node. (释放锁时才换出)
+type bpMutex[K, V any] struct {
+	sync.RWMutex
+	pool *bpPool[K, V] // The buffer pool of the file, nil without one; set and cleared under the write lock.
+}
+
+// Unlock evicts the nodes over the capacity of the pool, then releases the write lock.
+func (m *bpMutex[K, V]) Unlock() {
+	if m.pool != nil {
+		m.pool.evict()
+	}
+	m.RWMutex.Unlock()
+}
+
+// RUnlock releases the read lock. When the pool is over its capacity, the nodes are evicted
+// if the write lock can be taken at once, otherwise the next one to release the write lock evicts them.
+func (m *bpMutex[K, V]) RUnlock() {
+	pool := m.pool
+	m.RWMutex.RUnlock()
+	if pool != nil && pool.over() && m.RWMutex.TryLock() {
+		if m.pool == pool {
+			pool.evict()
+		}
+		m.RWMutex.Unlock()
+	}
+}
+
+// bpPool is the buffer pool of a file, it decides which data nodes of the file keep their items in memory.
+// The frames hold the data nodes that are written in the file, with their items or evicted.
+// The tree lock keeps eviction away from the operations, see bpMutex, and the pool lock keeps the faults
+// of concurrent readers and the pins of snapshots apart. (CLOCK 置换，钉住的节点不会被换出，只换出干净的节点)
+type bpPool[K, V any] struct {
+	mutex    sync.Mutex
+	file     *bpFile[K, V] // Only the file and the codecs are used, the rest changes under the write lock.
+	pageSize int
+	order    byteOrder
+	cfg      *bpConfig[K]
+	capacity int
+	frames   []*poolFrame[K, V] // The data nodes of the pool, the CLOCK hand goes round them.
+	hand     int                // The next frame the CLOCK hand considers for eviction.
+	resident atomic.Int64       // The frames with their items in memory.
+	hits     atomic.Uint64      // Counted by the readers without the pool lock.
+	shared   uint64             // The generation of the last Snapshot, the older nodes may still be read by a snapshot.
+	counters PoolStats          // Misses, Evictions and WriteBacks, under the pool lock.
+}
+
+// poolFrame is the place of a data node in the buffer pool.
+type poolFrame[K, V any] struct {
+	pool    *bpPool[K, V]
+	data    *BpDataG[K, V]
+	slot    int         // The position in frames.
+	pins    int         // The snapshots reading the node, a pinned node is never evicted.
+	live    int         // The unmasked items, kept for the item counts while the items are evicted.
+	ref     atomic.Bool // The node was read since the hand last passed, it gets a second chance.
+	evicted atomic.Bool // The items were dropped, the next read faults them in.
+}
+
+// newPool creates the buffer pool of the file, pages of zero takes the default size.
+func newPool[K, V any](file *bpFile[K, V], cfg *bpConfig[K], pages int) *bpPool[K, V] {
+	if pages == 0 {
+		pages = defaultPoolPages(int(file.header.pageSize))
+	}
+	return &bpPool[K, V]{
+		file:     file,
+		pageSize: int(file.header.pageSize),
+		order:    file.header.order,
+		cfg:      cfg,
+		capacity: max(pages, minPoolPages),
+	}
+}
+
+// defaultPoolPages sizes the pool from the available memory.
+func defaultPoolPages(pageSize int) int {
+	available, err := utilhub.GetLinuxAvailableMemory() // in kB, the same as /proc/meminfo
+	if err != nil || available == 0 {
+		return fallbackPoolSize
+	}
+	return int(min(available*1024/poolMemoryShare/uint64(pageSize), 1<<30))
+}
+
+// items returns the items of the data node, the buffer pool faults them in when they were evicted.
+// The caller holds the tree lock, a snapshot reads with pin instead.
+func (data *BpDataG[K, V]) items() []BpItemG[K, V] {
+	if data.frame != nil {
+		data.frame.touch()
+	}
+	return data.Items
+}
+
+// touch counts a read of the node in the frame, and faults the items in when they were evicted.
+func (frame *poolFrame[K, V]) touch() {
+	if frame.evicted.Load() {
+		frame.pool.fault(frame)
+		return
+	}
+	frame.ref.Store(true)
+	frame.pool.hits.Add(1)
+}
+
+// scan returns the items of the data node for a walk that visits every node once, such as Checkpoint.
+// An evicted node is read from its page and stays evicted, so the walk does not fill the pool.
+// The caller holds the tree lock.
+func (data *BpDataG[K, V]) scan() []BpItemG[K, V] {
+	if frame := data.frame; frame != nil && frame.evicted.Load() {
+		return frame.pool.read(frame, false)
+	}
+	return data.items()
+}
+
+// add puts a data node into a frame with its items in memory, written tells a node Flush has just written back
+// from one Open has read. The caller holds the write lock.
+func (pool *bpPool[K, V]) add(data *BpDataG[K, V], written bool) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	frame := &poolFrame[K, V]{pool: pool, data: data, slot: len(pool.frames)}
+	if written {
+		pool.counters.WriteBacks++
+		frame.ref.Store(true)
+	} else {
+		pool.counters.Misses++
+	}
+	pool.frames = append(pool.frames, frame)
+	pool.resident.Add(1)
+	data.frame = frame
+}
+
+// fault reads the items of an evicted node back from its page.
+func (pool *bpPool[K, V]) fault(frame *poolFrame[K, V]) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	// Another reader may have faulted it in while this one waited.
+	if frame.evicted.Load() {
+		frame.data.Items = pool.read(frame, true)
+		frame.ref.Store(true)
+		pool.resident.Add(1)
+		frame.evicted.Store(false)
+	}
+}
+
+// read reads the items of the frame from its page, the caller holds the pool lock when locked is true.
+// A snapshot may read while Flush runs, so it does not go through readPage, which looks at the page count.
+// ⚠️ The page was checked when it was written or opened, so a failure here means the file was changed
+// behind the tree or the disk fails, and the operation cannot go on.
+func (pool *bpPool[K, V]) read(frame *poolFrame[K, V], locked bool) []BpItemG[K, V] {
+	if !locked {
+		pool.mutex.Lock()
+		defer pool.mutex.Unlock()
+	}
+	pool.counters.Misses++
+	page := frame.data.page
+	buf := make([]byte, pool.pageSize)
+	_, err := pool.file.file.ReadAt(buf, int64(page)*int64(pool.pageSize))
+	var read *BpDataG[K, V]
+	if err == nil {
+		var r *pageReader
+		var kind byte
+		var n int
+		if r, kind, n, err = openPage(buf, pool.order, page); err == nil {
+			read, err = pool.file.decodeData(page, r, kind, n)
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, ErrCorruptPage) {
+			err = fmt.Errorf("%w: %w", ErrCorruptPage, err)
+		}
+		panic(fmt.Errorf("fault in page %d: %w", page, err))
+	}
+	return read.Items
+}
+
+// pin returns the items of the data node for a snapshot that reads it without the tree lock,
+// the node is not evicted until it is unpinned. A node outside the pool is returned as it is.
+func (pool *bpPool[K, V]) pin(data *BpDataG[K, V]) []BpItemG[K, V] {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	frame := data.frame
+	if frame == nil {
+		return data.Items
+	}
+	if frame.evicted.Load() {
+		data.Items = pool.read(frame, true)
+		pool.resident.Add(1)
+		frame.evicted.Store(false)
+	} else {
+		pool.hits.Add(1)
+	}
+	frame.pins++
+	frame.ref.Store(true)
+	return data.Items
+}
+
+// unpin releases a node pin returned.
+func (pool *bpPool[K, V]) unpin(data *BpDataG[K, V]) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if frame := data.frame; frame != nil {
+		frame.pins--
+	}
+}
+
+// over reports whether more nodes than the capacity have their items in memory.
+func (pool *bpPool[K, V]) over() bool {
+	return pool.resident.Load() > int64(pool.capacity)
+}
+
+// evict moves the CLOCK hand until the pool is within its capacity. The hand passes over the evicted
+// and the pinned nodes, and clears the reference of the ones read since it last passed; two full turns
+// without a victim mean the rest is pinned. The caller holds the write lock, so no operation reads the items.
+func (pool *bpPool[K, V]) evict() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for step := 0; pool.over() && step < 2*len(pool.frames); step++ {
+		frame := pool.frames[pool.hand]
+		pool.hand = (pool.hand + 1) % len(pool.frames)
+		switch {
+		case frame.evicted.Load() || frame.pins > 0:
+			// ⚠️ A pinned node is in use, it is never evicted.
+		case frame.ref.Swap(false):
+			// The node gets a second chance.
+		default:
+			frame.live = frame.data.live(pool.cfg)
+			frame.data.Items = nil
+			frame.evicted.Store(true)
+			pool.resident.Add(-1)
+			pool.counters.Evictions++
+			step = 0
+		}
+	}
+}
+
+// release takes the nodes whose pages the new version no longer uses out of the pool, they are not in the tree
+// anymore and their pages are reused by the next Flush. An evicted one a snapshot may still read is faulted in
+// first, while its page still holds it. The caller holds the write lock.
+func (pool *bpPool[K, V]) release(used []bool) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for i := len(pool.frames) - 1; i >= 0; i-- {
+		frame := pool.frames[i]
+		if page := int(frame.data.page); page < len(used) && used[page] {
+			continue
+		}
+		if !frame.evicted.Load() {
+			pool.resident.Add(-1)
+		} else if frame.data.gen < pool.shared {
+			frame.data.Items = pool.read(frame, true)
+		}
+		pool.remove(frame)
+	}
+}
+
+// detach faults every evicted node in and empties the pool, the tree lives in memory afterward.
+// It stops at the first page that cannot be read. The caller holds the write lock.
+func (pool *bpPool[K, V]) detach() (err error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			if err, _ = r.(error); err == nil {
+				panic(r)
+			}
+		}
+	}()
+	for len(pool.frames) > 0 {
+		frame := pool.frames[len(pool.frames)-1]
+		if frame.evicted.Load() {
+			frame.data.Items = pool.read(frame, true)
+		} else {
+			pool.resident.Add(-1)
+		}
+		pool.remove(frame)
+	}
+	return
+}
+
+// remove takes the frame out of the pool, the last frame takes its slot. The caller holds the pool lock.
+func (pool *bpPool[K, V]) remove(frame *poolFrame[K, V]) {
+	last := pool.frames[len(pool.frames)-1]
+	last.slot, pool.frames[frame.slot] = frame.slot, last
+	pool.frames = pool.frames[:len(pool.frames)-1]
+	if pool.hand >= len(pool.frames) {
+		pool.hand = 0
+	}
+	frame.data.frame = nil
+	frame.evicted.Store(false)
+}
+
+// stats returns a copy of the counters.
+func (pool *bpPool[K, V]) stats() (stats PoolStats) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	stats = pool.counters
+	stats.Hits = pool.hits.Load()
+	stats.Resident, stats.Capacity = int(pool.resident.Load()), pool.capacity
+	return
+}
@@ -2,7 +2,7 @@ package bpTree
 
 import "fmt"
 
-func (inode *BpIndex) Print() {
+func (inode *BpIndexG[K, V]) Print() {
 	fmt.Println()
 	fmt.Println("[⭕️IndexNode]:", inode.Index)
 
@@ -18,13 +18,13 @@ func (inode *BpIndex) Print() {
 	}
 }
 
-func (data *BpData) _print() {
-	for _, item := range data.Items {
-		fmt.Printf("Key: %d\n", item.Key)
+func (data *BpDataG[K, V]) _print() {
+	for _, item := range data.items() {
+		fmt.Printf("Key: %v\n", item.Key)
 	}
 }
 
-func (inode *BpIndex) BpDataHead() (head *BpData) {
+func (inode *BpIndexG[K, V]) BpDataHead() (head *BpDataG[K, V]) {
 	current := inode
 	for {
 		if len(current.DataNodes) == 0 {
@@ -35,7 +35,7 @@ func (inode *BpIndex) BpDataHead() (head *BpData) {
 	}
 }
 
-func (inode *BpIndex) BpDataTail() (head *BpData) {
+func (inode *BpIndexG[K, V]) BpDataTail() (head *BpDataG[K, V]) {
 	current := inode
 	for {
 		if len(current.DataNodes) == 0 {
@@ -48,15 +48,16 @@ func (inode *BpIndex) BpDataTail() (head *BpData) {
 	}
 }
 
-func (data *BpData) PrintAscent() {
+func (data *BpDataG[K, V]) PrintAscent() {
 	current := data
 	nodeNumber := 0
 
 	for current != nil {
 		fmt.Printf("[🟣 DataNode]: NO %d \n", nodeNumber)
-		length := len(current.Items)
+		items := current.items()
+		length := len(items)
 		for i := 0; i < length; i++ {
-			fmt.Printf("Key: %d\n", current.Items[i].Key)
+			fmt.Printf("Key: %v\n", items[i].Key)
 		}
 
 		nodeNumber++
@@ -64,15 +65,16 @@ func (data *BpData) PrintAscent() {
 	}
 }
 
-func (data *BpData) PrintDescent() {
+func (data *BpDataG[K, V]) PrintDescent() {
 	current := data
 	nodeNumber := 0
 
 	for current != nil {
 		fmt.Printf("[🟣 DataNode]: NO %d \n", nodeNumber)
-		length := len(current.Items)
+		items := current.items()
+		length := len(items)
 		for i := length - 1; i >= 0; i-- {
-			fmt.Printf("Key: %d\n", current.Items[i].Key)
+			fmt.Printf("Key: %v\n", items[i].Key)
 		}
 
 		nodeNumber++
@@ -80,15 +82,16 @@ func (data *BpData) PrintDescent() {
 	}
 }
 
-func (data *BpData) PrintNodeAscent(number int) (keys []int64) {
+func (data *BpDataG[K, V]) PrintNodeAscent(number int) (keys []K) {
 	current := data
 	nodeNumber := 0
 
 	for current != nil {
 		if nodeNumber == number {
-			length := len(current.Items)
+			items := current.items()
+			length := len(items)
 			for i := 0; i < length; i++ {
-				keys = append(keys, current.Items[i].Key)
+				keys = append(keys, items[i].Key)
 			}
 			return
 		}
@@ -100,15 +103,16 @@ func (data *BpData) PrintNodeAscent(number int) (keys []int64) {
 	return
 }
 
-func (data *BpData) PrintNodeDescent(number int) (keys []int64) {
+func (data *BpDataG[K, V]) PrintNodeDescent(number int) (keys []K) {
 	current := data
 	nodeNumber := 0
 
 	for current != nil {
 		if nodeNumber == number {
-			length := len(current.Items)
+			items := current.items()
+			length := len(items)
 			for i := length - 1; i >= 0; i-- {
-				keys = append(keys, current.Items[i].Key)
+				keys = append(keys, items[i].Key)
 			}
 			return
 		}
@@ -0,0 +1,231 @@
+package bpTree
+
+import (
+	"sort"
+)
+
+// ➡️ order-statistic operation
+
+// Len ensures thread safety, returns the number of unmasked items in B plus tree, release lock.
+func (tree *BpTreeG[K, V]) Len() (length int) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// The root counts every unmasked item.
+	length = tree.root.count
+
+	// Performing a return.
+	return
+}
+
+// Rank ensures thread safety, returns the number of unmasked items whose key is less than the key, release lock.
+// It is the position of the first item with the key, or where the key would be inserted. (名次，从 0 开始)
+func (tree *BpTreeG[K, V]) Rank(key K) (rank int) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Performing the search.
+	rank = tree.root.rank(tree.cfg, key)
+
+	// Performing a return.
+	return
+}
+
+// Select ensures thread safety, returns the unmasked item at position i in ascending order, release lock.
+// Position 0 is the smallest item; found is false when i is out of range. (取第 i 小的资料)
+func (tree *BpTreeG[K, V]) Select(i int) (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Out of range.
+	if i < 0 || i >= tree.root.count {
+		return
+	}
+
+	// Performing the search.
+	item, found = tree.root.selectAt(tree.cfg, i), true
+
+	// Performing a return.
+	return
+}
+
+// CountRange ensures thread safety, returns the number of unmasked items with from <= key < to, release lock.
+func (tree *BpTreeG[K, V]) CountRange(from, to K) (count int) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// An empty range.
+	if tree.cfg.compare(from, to) >= 0 {
+		return
+	}
+
+	// The difference between the two ranks.
+	count = tree.root.rank(tree.cfg, to) - tree.root.rank(tree.cfg, from)
+
+	// Performing a return.
+	return
+}
+
+// rank counts the items whose key is less than the key.
+// The keys of child i are not greater than Index[i], and the keys of child i+1 are not less than it,
+// so only the first child with Index[i] >= key can hold both smaller and larger keys.
+func (inode *BpIndexG[K, V]) rank(cfg *bpConfig[K], key K) (rank int) {
+	current := inode
+	for {
+		ix := sort.Search(len(current.Index), func(i int) bool {
+			return cfg.compare(current.Index[i], key) >= 0
+		})
+
+		// Count the children before ix and descend into ix.
+		if len(current.IndexNodes) > 0 {
+			ix = min(ix, len(current.IndexNodes)-1)
+			for _, child := range current.IndexNodes[:ix] {
+				rank += child.count
+			}
+			current = current.IndexNodes[ix]
+			continue
+		}
+
+		// Reach the data nodes, the masked items do not count.
+		ix = min(ix, len(current.DataNodes)-1)
+		for _, data := range current.DataNodes[:ix] {
+			rank += data.live(cfg)
+		}
+		for _, item := range current.DataNodes[ix].items() {
+			if cfg.compare(item.Key, key) >= 0 {
+				break
+			}
+			if !item.Mask {
+				rank++
+			}
+		}
+		return
+	}
+}
+
+// selectAt returns the item at position i of the subtree, i must be within the count.
+func (inode *BpIndexG[K, V]) selectAt(cfg *bpConfig[K], i int) (item BpItemG[K, V]) {
+	current := inode
+	for len(current.IndexNodes) > 0 {
+		// Skip the children before the position.
+		next := current.IndexNodes[len(current.IndexNodes)-1]
+		for _, child := range current.IndexNodes {
+			if i < child.count {
+				next = child
+				break
+			}
+			i -= child.count
+		}
+		current = next
+	}
+
+	// Skip the data nodes before the position, then the unmasked items in the data node.
+	for _, data := range current.DataNodes {
+		if live := data.live(cfg); i >= live {
+			i -= live
+			continue
+		}
+		for _, item = range data.items() {
+			if item.Mask {
+				continue
+			}
+			if i == 0 {
+				return
+			}
+			i--
+		}
+	}
+	return
+}
+
+// recount renews the item counts after a modification of the key, the caller holds the lock.
+// A modification changes the items of the data nodes around the key, and a split, a borrow or a merge
+// moves whole children between the nodes next to that path; a moved child keeps its count,
+// only the nodes that gain or lose it have to add up their children again.
+// So recount walks the children that may hold the key, the nearest edge of the neighbors,
+// and adds up the siblings one step further. (修改后沿着路径和邻居的边缘重新计算资料数量)
+func (inode *BpIndexG[K, V]) recount(cfg *bpConfig[K], key K) {
+	// The bottom index node counts the items of its data nodes.
+	if len(inode.IndexNodes) == 0 {
+		inode.sumCount(cfg)
+		return
+	}
+
+	// The children from first to last may hold the key; after a deletion the key may have been
+	// the edge of the child after them, so the neighbors are walked as well.
+	first, last := inode.childrenOf(cfg, key)
+
+	for i := max(first-2, 0); i <= min(last+2, len(inode.IndexNodes)-1); i++ {
+		switch child := inode.IndexNodes[i]; {
+		case i == first-1:
+			child.recountEdge(cfg, true)
+		case i == last+1:
+			child.recountEdge(cfg, false)
+		case i < first || i > last:
+			child.sumCount(cfg)
+		default:
+			child.recount(cfg, key)
+		}
+	}
+	inode.sumCount(cfg)
+}
+
+// childrenOf returns the positions of the first and the last index child that may hold the key.
+func (inode *BpIndexG[K, V]) childrenOf(cfg *bpConfig[K], key K) (first, last int) {
+	first = sort.Search(len(inode.Index), func(i int) bool {
+		return cfg.compare(inode.Index[i], key) >= 0
+	})
+	last = sort.Search(len(inode.Index), func(i int) bool {
+		return cfg.compare(inode.Index[i], key) > 0
+	})
+	last = min(last, len(inode.IndexNodes)-1)
+	first = min(first, last)
+	return
+}
+
+// recountEdge renews the item counts along the rightmost path of the subtree when right is true,
+// or along the leftmost path otherwise; the sibling next to the path is added up as well.
+func (inode *BpIndexG[K, V]) recountEdge(cfg *bpConfig[K], right bool) {
+	if n := len(inode.IndexNodes); n > 0 {
+		edge, sibling := 0, 1
+		if right {
+			edge, sibling = n-1, n-2
+		}
+		inode.IndexNodes[edge].recountEdge(cfg, right)
+		if sibling >= 0 && sibling < n {
+			inode.IndexNodes[sibling].sumCount(cfg)
+		}
+	}
+	inode.sumCount(cfg)
+}
+
+// sumCount adds up the counts of the children.
+func (inode *BpIndexG[K, V]) sumCount(cfg *bpConfig[K]) {
+	inode.count = 0
+	for _, child := range inode.IndexNodes {
+		inode.count += child.count
+	}
+	for _, data := range inode.DataNodes {
+		inode.count += data.live(cfg)
+	}
+}
+
+// recountAll renews the item counts of the whole subtree.
+func (inode *BpIndexG[K, V]) recountAll(cfg *bpConfig[K]) {
+	for _, child := range inode.IndexNodes {
+		child.recountAll(cfg)
+	}
+	inode.sumCount(cfg)
+}
@@ -0,0 +1,200 @@
+package bpTree
+
+import (
+	"slices"
+)
+
+// ➡️ rebalance operation
+
+// removeAlong descends along the child positions in path and removes the item at ix of the data node at the bottom.
+// On the way back up, every node joins the children that fall below the minimum with a neighbor,
+// then renews its index keys and its item count. The caller owns the node.
+func (inode *BpIndexG[K, V]) removeAlong(cfg *bpConfig[K], gen uint64, path []int, ix int) (item BpItemG[K, V]) {
+	if len(inode.IndexNodes) == 0 {
+		// Reach the data node.
+		data := inode.ownData(gen, path[0])
+		item = data.Items[ix]
+
+		// In lazy deletion mode, the masked item no longer counts, and nothing else changes.
+		if cfg.lazy {
+			data.Items[ix].Mask = true
+			data.trace(cfg, TraceMask)
+			inode.count--
+			return
+		}
+
+		// Neighbors may share the backing array after borrowing, so the deletion makes a new one.
+		data.Items = append(slices.Clip(data.Items[:ix]), data.Items[ix+1:]...)
+	} else {
+		// Descend first, the children below are fixed before this node.
+		item = inode.ownIndex(gen, path[0]).removeAlong(cfg, gen, path[1:], ix)
+		if cfg.lazy {
+			inode.count--
+			return
+		}
+	}
+
+	// Fix the children on the way back up.
+	inode.fixChildren(cfg, gen)
+	inode.renewKeys()
+	inode.sumCount(cfg)
+
+	// Performing a return.
+	return
+}
+
+// fixChildren joins the children that fall below the minimum with a neighbor, the caller owns the node.
+// When this node has only one child, the parent of this node takes care of it instead.
+func (inode *BpIndexG[K, V]) fixChildren(cfg *bpConfig[K], gen uint64) {
+	for i := 0; i < inode.children() && inode.children() > 1; {
+		if !inode.underfull(cfg, i) {
+			i++
+			continue
+		}
+
+		// Join with the left neighbor, or with the right neighbor for the first child,
+		// then look at the joined child again, it may still be too small. (和邻居合拼或平分后再检查一次)
+		i = max(i-1, 0)
+		inode.join(cfg, gen, i)
+	}
+}
+
+// children returns the number of child nodes, index nodes or data nodes.
+func (inode *BpIndexG[K, V]) children() int {
+	return len(inode.IndexNodes) + len(inode.DataNodes)
+}
+
+// underfull reports whether the child at i holds fewer entries than a split leaves behind,
+// then it has to be joined with a neighbor. The masked items still take their place in a data node.
+func (inode *BpIndexG[K, V]) underfull(cfg *bpConfig[K], i int) bool {
+	if len(inode.IndexNodes) > 0 {
+		return len(inode.IndexNodes[i].Index) < cfg.minIndex()
+	}
+	return len(inode.DataNodes[i].items()) < cfg.minItems()
+}
+
+// join merges the children at pos and pos+1, or shares out their entries when they do not fit in one node.
+// Both children are cloned first in copy-on-write mode.
+func (inode *BpIndexG[K, V]) join(cfg *bpConfig[K], gen uint64, pos int) {
+	// The data children.
+	if len(inode.IndexNodes) == 0 {
+		inode.ownData(gen, pos)
+		inode.ownData(gen, pos+1)
+		inode.joinData(cfg, pos)
+		return
+	}
+
+	// The index children, the children they take over may be too small as well, so they are fixed again.
+	left, right := inode.ownIndex(gen, pos), inode.ownIndex(gen, pos+1)
+	joined := []*BpIndexG[K, V]{left}
+	if !inode.mergeChildren(cfg, pos) {
+		joined = append(joined, right)
+	}
+	for _, child := range joined {
+		child.fixChildren(cfg, gen)
+		child.sumCount(cfg)
+	}
+}
+
+// joinData merges the data children at pos and pos+1 when they fit in one data node,
+// otherwise it shares out their items half and half. The caller owns both data nodes.
+// (合拼相邻的两个资料节点，太大时改为平分)
+func (inode *BpIndexG[K, V]) joinData(cfg *bpConfig[K], pos int) {
+	left, right := inode.DataNodes[pos], inode.DataNodes[pos+1]
+
+	// Neighbors may share the backing array after borrowing, so the joining makes a new one.
+	items := append(slices.Clip(left.Items), right.Items...)
+
+	// Merge them into the left data node when they fit.
+	if len(items) < cfg.width {
+		left.Items = items
+		right.unlink()
+		inode.Index = slices.Delete(inode.Index, pos, pos+1)
+		inode.DataNodes = slices.Delete(inode.DataNodes, pos+1, pos+2)
+		left.trace(cfg, TraceMerge)
+		return
+	}
+
+	// Otherwise share them out, the data node with fewer items borrows from the other one. (较小的一方向邻居借)
+	leftShort := len(left.Items) < len(right.Items)
+	half := len(items) / 2
+	left.Items, right.Items = items[:half:half], items[half:]
+	inode.Index[pos] = right.Items[0].Key
+	if leftShort {
+		left.trace(cfg, TraceBorrowRight)
+	} else {
+		right.trace(cfg, TraceBorrowLeft)
+	}
+}
+
+// mergeChildren merges the index children at pos and pos+1, and reports whether they were merged.
+// When the merged node would reach the width, the entries are shared out between the two children instead.
+// The caller owns both children. (合拼相邻的两个子节点，太大时改为平分)
+func (inode *BpIndexG[K, V]) mergeChildren(cfg *bpConfig[K], pos int) (merged bool) {
+	left, right := inode.IndexNodes[pos], inode.IndexNodes[pos+1]
+
+	// The separator is the edge value of the right child.
+	separator := inode.Index[pos]
+	if items := right.BpDataHead().items(); len(items) > 0 {
+		separator = items[0].Key
+	}
+
+	// Put the index keys and the children of both sides together.
+	index := make([]K, 0, len(left.Index)+len(right.Index)+1)
+	index = append(index, left.Index...)
+	index = append(index, separator)
+	index = append(index, right.Index...)
+	indexNodes := append(append([]*BpIndexG[K, V]{}, left.IndexNodes...), right.IndexNodes...)
+	dataNodes := append(append([]*BpDataG[K, V]{}, left.DataNodes...), right.DataNodes...)
+
+	// Merge them into the left child when they fit.
+	if len(index) < cfg.width {
+		left.Index, left.IndexNodes, left.DataNodes = index, indexNodes, dataNodes
+		inode.Index = slices.Delete(inode.Index, pos, pos+1)
+		inode.IndexNodes = slices.Delete(inode.IndexNodes, pos+1, pos+2)
+		left.trace(cfg, TraceMerge)
+		return true
+	}
+
+	// Otherwise share them out, and the middle key moves up into this node.
+	// The child with fewer index keys borrows from the other one. (较小的一方向邻居借)
+	leftShort := len(left.Index) < len(right.Index)
+	half := (len(index) + 1) / 2 // The number of children on the left.
+	left.Index = append([]K{}, index[:half-1]...)
+	right.Index = append([]K{}, index[half:]...)
+	inode.Index[pos] = index[half-1]
+	if len(indexNodes) > 0 {
+		left.IndexNodes = append([]*BpIndexG[K, V]{}, indexNodes[:half]...)
+		right.IndexNodes = append([]*BpIndexG[K, V]{}, indexNodes[half:]...)
+	} else {
+		left.DataNodes = append([]*BpDataG[K, V]{}, dataNodes[:half]...)
+		right.DataNodes = append([]*BpDataG[K, V]{}, dataNodes[half:]...)
+	}
+	if leftShort {
+		left.trace(cfg, TraceBorrowRight)
+	} else {
+		right.trace(cfg, TraceBorrowLeft)
+	}
+	return false
+}
+
+// eachData calls fn for every data node in the subtree.
+func (inode *BpIndexG[K, V]) eachData(fn func(data *BpDataG[K, V])) {
+	for _, child := range inode.IndexNodes {
+		child.eachData(fn)
+	}
+	for _, data := range inode.DataNodes {
+		fn(data)
+	}
+}
+
+// unlink takes the data node out of the links between data nodes.
+func (data *BpDataG[K, V]) unlink() {
+	if data.Previous != nil {
+		data.Previous.Next = data.Next
+	}
+	if data.Next != nil {
+		data.Next.Previous = data.Previous
+	}
+	data.Previous, data.Next = nil, nil
+}
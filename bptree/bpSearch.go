@@ -0,0 +1,139 @@
+package bpTree
+
+import (
+	"sort"
+)
+
+// ➡️ search operation
+
+// Get returns the first unmasked item with the given key.
+func (tree *BpTreeG[K, V]) Get(key K) (item BpItemG[K, V], found bool) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Performing the search.
+	item, found = tree.root.search(tree.cfg, key)
+
+	// Performing a return.
+	return
+}
+
+// Contains reports whether an unmasked item with the given key exists.
+func (tree *BpTreeG[K, V]) Contains(key K) (found bool) {
+	_, found = tree.Get(key)
+	return
+}
+
+// search descends to the data node and returns the first unmasked item with the given key.
+func (inode *BpIndexG[K, V]) search(cfg *bpConfig[K], key K) (item BpItemG[K, V], found bool) {
+	var data *BpDataG[K, V]
+	var ix int
+	if data, ix, found = inode.locate(cfg, key); found {
+		item = data.items()[ix]
+	}
+	return
+}
+
+// locate descends to the data node and returns the position of the first unmasked item with the given key,
+// so that the caller can change the item in place.
+func (inode *BpIndexG[K, V]) locate(cfg *bpConfig[K], key K) (data *BpDataG[K, V], ix int, found bool) {
+	// Find the first item whose key is not less than the key.
+	data, ix = inode.searchBpData(cfg, key).lowerBound(cfg, key)
+
+	// Walk through the duplicates and skip the masked ones. (跳过被遮罩的资料)
+	for data != nil {
+		items := data.items()
+		for ; ix < len(items); ix++ {
+			if cfg.compare(items[ix].Key, key) != 0 {
+				return
+			}
+			if !items[ix].Mask {
+				found = true
+				return
+			}
+		}
+
+		// The duplicates may continue in the next data node.
+		data, ix = data.Next, 0
+	}
+
+	// Performing a return.
+	return
+}
+
+// locateLast returns the position of the last unmasked item with the given key, the newest of the duplicates.
+func (inode *BpIndexG[K, V]) locateLast(cfg *bpConfig[K], key K) (data *BpDataG[K, V], ix int, found bool) {
+	// Every item before the upper bound is not greater than the key.
+	if data, ix = inode.upperBound(cfg, key); data == nil { // Start from the tail. (从尾端开始)
+		data = inode.BpDataTail()
+		ix = len(data.items())
+	}
+
+	// Step back over the masked items.
+	if data, ix = data.backward(ix - 1); data != nil {
+		found = cfg.compare(data.items()[ix].Key, key) == 0
+	}
+	return
+}
+
+// searchBpData descends the index with the same binary search insertItem uses and returns the data node for the key.
+// (和 insertItem 一样的二分法，一路找到资料节点)
+func (inode *BpIndexG[K, V]) searchBpData(cfg *bpConfig[K], key K) (data *BpDataG[K, V]) {
+	current := inode
+	for {
+		// Use binary search to find the index (ix); no equal sign, so equal keys go to the right.
+		ix := sort.Search(len(current.Index), func(i int) bool {
+			return cfg.compare(current.Index[i], key) > 0
+		})
+
+		// Descend into the index nodes.
+		if len(current.IndexNodes) > 0 {
+			if ix > len(current.IndexNodes)-1 { // The index may not be updated on time. (索引可能没有及时更新)
+				ix = len(current.IndexNodes) - 1
+			}
+			current = current.IndexNodes[ix]
+			continue
+		}
+
+		// Reach the data nodes.
+		if ix > len(current.DataNodes)-1 {
+			ix = len(current.DataNodes) - 1
+		}
+		data = current.DataNodes[ix]
+		return
+	}
+}
+
+// lowerBound moves along the data node links to the first item whose key is not less than the key.
+// It returns nil when every item is smaller than the key.
+// Duplicates may span several data nodes, so the search steps back to the left first. (相同值可能横跨多个资料节点)
+func (data *BpDataG[K, V]) lowerBound(cfg *bpConfig[K], key K) (node *BpDataG[K, V], ix int) {
+	// Step back while the previous data node may still hold the key.
+	node = data
+	for node.Previous != nil {
+		items := node.Previous.items()
+		if length := len(items); length > 0 && cfg.compare(items[length-1].Key, key) < 0 {
+			break
+		}
+		node = node.Previous
+	}
+
+	// Step forward until an item is not less than the key.
+	for node != nil {
+		items := node.items()
+		ix = sort.Search(len(items), func(i int) bool {
+			return cfg.compare(items[i].Key, key) >= 0
+		})
+		if ix < len(items) {
+			return
+		}
+		node = node.Next
+	}
+
+	// Every item is smaller than the key.
+	ix = 0
+	return
+}
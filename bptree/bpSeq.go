@@ -0,0 +1,73 @@
+package bpTree
+
+import (
+	"iter"
+)
+
+// ➡️ range-over-func iterators
+
+// seqChunk is the number of items the range-over-func iterators copy under the read lock at a time.
+const seqChunk = 64
+
+// All returns the unmasked items in ascending order, for use as `for key, val := range tree.All()`.
+// The read lock is only held while a chunk of items is copied, and it is released before they are yielded,
+// so the loop body may call the tree, even modify it. (迴圈内可以操作树)
+// After a modification the walk finds its position again by key, the same as BpIterator.
+func (tree *BpTreeG[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// Walk forward from the head of the data nodes.
+		it := tree.Iterator()
+		it.walk(it.first, it.next, func(K) bool { return true }, yield)
+	}
+}
+
+// Backward returns the unmasked items in descending order.
+// The read lock is released before the items are yielded, the same as All.
+func (tree *BpTreeG[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// Walk backward from the tail of the data nodes.
+		it := tree.Iterator()
+		it.walk(it.last, it.prev, func(K) bool { return true }, yield)
+	}
+}
+
+// Ascend returns the unmasked items with from <= key < to in ascending order.
+// The read lock is released before the items are yielded, the same as All.
+func (tree *BpTreeG[K, V]) Ascend(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		// Walk forward from the first item not less than from.
+		it := tree.Iterator()
+		start := func() bool { return it.seek(from) }
+		it.walk(start, it.next, func(key K) bool { return tree.cfg.compare(key, to) < 0 }, yield)
+	}
+}
+
+// walk yields the items the iterator visits while in accepts their keys. start positions the iterator
+// and step moves it on, both are called with the read lock held. The items are copied in chunks of seqChunk
+// under the read lock, and the lock is released before they are yielded. (分批复制，释放锁后再交出)
+func (it *BpIteratorG[K, V]) walk(start, step func() bool, in func(key K) bool, yield func(K, V) bool) {
+	items := make([]BpItemG[K, V], 0, seqChunk)
+	move := start
+	for {
+		// Copy the next chunk under the read lock.
+		items = items[:0]
+		it.tree.mutex.RLock()
+		for len(items) < seqChunk && move() && in(it.item.Key) {
+			items = append(items, it.item)
+			move = step
+		}
+		it.tree.mutex.RUnlock()
+
+		// Yield them without the lock.
+		for _, item := range items {
+			if !yield(item.Key, item.Val) {
+				return
+			}
+		}
+
+		// A short chunk is the last one.
+		if len(items) < seqChunk {
+			return
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package bpTree
+
+import (
+	"iter"
+	"slices"
+	"sort"
+)
+
+// ➡️ copy-on-write and snapshot
+
+// WithCopyOnWrite makes the modifications clone the nodes they touch instead of changing them in place,
+// so that Snapshot can hand out point-in-time views that are read without the tree lock.
+// (写入时复制路径上的节点，快照读取不需要锁)
+func WithCopyOnWrite() BpOption {
+	return func(opts *bpOptions) {
+		opts.cow = true
+	}
+}
+
+// BpSnapshotG is an immutable, point-in-time view of B plus tree.
+// It is read without the tree lock, and it can be used by several goroutines at the same time.
+//
+// ⚠️ A snapshot never follows the Previous and Next links between data nodes.
+// The links belong to the live tree: when a data node is cloned, its neighbors are relinked to the clone,
+// even when the neighbors are still shared with snapshots. A snapshot walks down from its root instead.
+// (快照不走资料节点的链结，链结只属于目前的树)
+type BpSnapshotG[K, V any] struct {
+	root *BpIndexG[K, V] // The root at the time of the snapshot.
+	cfg  *bpConfig[K]    // Only the compare function is used.
+	pool *bpPool[K, V]   // The buffer pool of the file, the data nodes are pinned while they are read; nil without a file.
+}
+
+// BpSnapshotG with int64 keys.
+type BpSnapshot = BpSnapshotG[int64, any]
+
+// Snapshot ensures thread safety, returns a point-in-time view of B plus tree, release lock.
+// It costs one generation bump; the nodes are cloned later, when a modification touches them.
+// The tree must be created with WithCopyOnWrite, otherwise ErrCopyOnWriteOff is returned.
+func (tree *BpTreeG[K, V]) Snapshot() (snap *BpSnapshotG[K, V], err error) {
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Without copy-on-write, the modifications would change the nodes the snapshot sees.
+	if !tree.cfg.cow {
+		err = ErrCopyOnWriteOff
+		return
+	}
+
+	// Every node that exists now belongs to an older generation, so it is shared and never changed again.
+	// (目前所有节点都变成旧世代，之后只会被复制，不会被修改)
+	tree.gen++
+	snap = &BpSnapshotG[K, V]{root: tree.root, cfg: tree.cfg}
+
+	// The buffer pool keeps the evicted nodes of this generation readable until the snapshot is done with them.
+	if tree.file != nil {
+		tree.file.pool.shared = tree.gen
+		snap.pool = tree.file.pool
+	}
+
+	// Performing a return.
+	return
+}
+
+// Get returns the first unmasked item with the given key in the snapshot.
+func (snap *BpSnapshotG[K, V]) Get(key K) (item BpItemG[K, V], found bool) {
+	for next := range snap.root.walk(snap.cfg, snap.pool, &key) {
+		if snap.cfg.compare(next.Key, key) == 0 {
+			item, found = next, true
+		}
+		break
+	}
+	return
+}
+
+// All returns the unmasked items of the snapshot in ascending order.
+func (snap *BpSnapshotG[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for item := range snap.root.walk(snap.cfg, snap.pool, nil) {
+			if !yield(item.Key, item.Val) {
+				return
+			}
+		}
+	}
+}
+
+// Ascend returns the unmasked items of the snapshot with from <= key < to in ascending order.
+func (snap *BpSnapshotG[K, V]) Ascend(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for item := range snap.root.walk(snap.cfg, snap.pool, &from) {
+			if snap.cfg.compare(item.Key, to) >= 0 || !yield(item.Key, item.Val) {
+				return
+			}
+		}
+	}
+}
+
+// Range returns the unmasked items of the snapshot with from <= key < to in ascending order.
+func (snap *BpSnapshotG[K, V]) Range(from, to K) (items []BpItemG[K, V]) {
+	for item := range snap.root.walk(snap.cfg, snap.pool, &from) {
+		if snap.cfg.compare(item.Key, to) >= 0 {
+			break
+		}
+		items = append(items, item)
+	}
+	return
+}
+
+// walk visits the unmasked items of the subtree in ascending order, starting from the first key not less than from.
+// A nil from starts from the smallest item. It follows the child nodes only, never the data node links.
+// A snapshot passes the buffer pool to pin the data nodes it reads, the callers holding the tree lock pass nil.
+func (inode *BpIndexG[K, V]) walk(cfg *bpConfig[K], pool *bpPool[K, V], from *K) iter.Seq[BpItemG[K, V]] {
+	return func(yield func(BpItemG[K, V]) bool) {
+		inode.walkFrom(cfg, pool, from, yield)
+	}
+}
+
+// walkFrom is the recursive part of walk, it returns false when yield asks to stop.
+func (inode *BpIndexG[K, V]) walkFrom(cfg *bpConfig[K], pool *bpPool[K, V], from *K, yield func(BpItemG[K, V]) bool) bool {
+	// The keys of child i are not greater than Index[i], so the children before it are skipped.
+	start := 0
+	if from != nil {
+		start = sort.Search(len(inode.Index), func(i int) bool {
+			return cfg.compare(inode.Index[i], *from) >= 0
+		})
+	}
+
+	for i := start; i < len(inode.IndexNodes); i++ {
+		if !inode.IndexNodes[i].walkFrom(cfg, pool, from, yield) {
+			return false
+		}
+	}
+	for i := start; i < len(inode.DataNodes); i++ {
+		if !inode.DataNodes[i].walkItems(cfg, pool, from, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// walkItems yields the unmasked items of the data node not less than from, it returns false when yield asks to stop.
+// With a buffer pool, the node stays pinned until the last item is yielded, so it is not evicted under the walk.
+func (data *BpDataG[K, V]) walkItems(cfg *bpConfig[K], pool *bpPool[K, V], from *K, yield func(BpItemG[K, V]) bool) bool {
+	var items []BpItemG[K, V]
+	if pool != nil {
+		items = pool.pin(data)
+		defer pool.unpin(data)
+	} else {
+		items = data.items()
+	}
+
+	for _, item := range items {
+		if item.Mask || (from != nil && cfg.compare(item.Key, *from) < 0) {
+			continue
+		}
+		if !yield(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// own makes the nodes an insertion of the key may touch writable, the caller holds the lock.
+// In copy-on-write mode the nodes of older generations are shared with snapshots, so they are cloned first.
+// The deletions clone the nodes they touch on their own way, see delFromRoot.
+func (tree *BpTreeG[K, V]) own(key K) {
+	// Nothing is shared before the first snapshot.
+	if !tree.cfg.cow || tree.gen == 0 {
+		return
+	}
+
+	// The new root is swapped in, the snapshot keeps the old one.
+	tree.ownRoot()
+	tree.root.own(tree.cfg, tree.gen, key)
+}
+
+// own clones the children whose range holds the key, then descends into them. (复制路径上的节点)
+func (inode *BpIndexG[K, V]) own(cfg *bpConfig[K], gen uint64, key K) {
+	// The bottom index node clones all its data nodes.
+	if len(inode.IndexNodes) == 0 {
+		for i := range inode.DataNodes {
+			inode.ownData(gen, i)
+		}
+		return
+	}
+
+	// The children from first to last hold the key, duplicates may span several of them.
+	first, last := inode.childrenOf(cfg, key)
+	for i := first; i <= last; i++ {
+		inode.ownIndex(gen, i).own(cfg, gen, key)
+	}
+}
+
+// ownRoot clones the root when it belongs to an older generation, the snapshot keeps the old one.
+func (tree *BpTreeG[K, V]) ownRoot() {
+	if tree.root.gen != tree.gen {
+		tree.root = tree.root.clone(tree.gen)
+	}
+}
+
+// ownIndex returns the index child at i, cloned first when it belongs to an older generation.
+// Without copy-on-write, every node stays in generation 0 and nothing is cloned.
+func (inode *BpIndexG[K, V]) ownIndex(gen uint64, i int) *BpIndexG[K, V] {
+	if inode.IndexNodes[i].gen != gen {
+		inode.IndexNodes[i] = inode.IndexNodes[i].clone(gen)
+	}
+	return inode.IndexNodes[i]
+}
+
+// ownData returns the data child at i, cloned first when it belongs to an older generation.
+func (inode *BpIndexG[K, V]) ownData(gen uint64, i int) *BpDataG[K, V] {
+	if inode.DataNodes[i].gen != gen {
+		inode.DataNodes[i] = inode.DataNodes[i].clone(gen)
+	}
+	return inode.DataNodes[i]
+}
+
+// clone copies the index node for the generation, the child nodes are still shared.
+// The nil slices stay nil, because the deletion tells the bottom index nodes by their DataNodes.
+func (inode *BpIndexG[K, V]) clone(gen uint64) *BpIndexG[K, V] {
+	return &BpIndexG[K, V]{
+		Index:      slices.Clone(inode.Index),
+		IndexNodes: slices.Clone(inode.IndexNodes),
+		DataNodes:  slices.Clone(inode.DataNodes),
+		count:      inode.count,
+		gen:        gen,
+	}
+}
+
+// clone copies the data node for the generation and relinks its neighbors to the copy.
+func (data *BpDataG[K, V]) clone(gen uint64) (copied *BpDataG[K, V]) {
+	copied = &BpDataG[K, V]{
+		Previous:         data.Previous,
+		Next:             data.Next,
+		Items:            slices.Clone(data.scan()),
+		ShouldRenewIndex: data.ShouldRenewIndex,
+		gen:              gen,
+	}
+
+	// The links belong to the live tree, the neighbors point to the copy from now on.
+	if data.Previous != nil {
+		data.Previous.Next = copied
+	}
+	if data.Next != nil {
+		data.Next.Previous = copied
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package bpTree
+
+// ➡️ trace operation
+
+// TraceKind tells which structural change a TraceEventG reports.
+type TraceKind int
+
+const (
+	TraceSplit         TraceKind = iota + 1 // A node reaches the width and is split in two. (分裂)
+	TraceBorrowLeft                         // A node borrows data from its left neighbor. (向左借资料)
+	TraceBorrowRight                        // A node borrows data from its right neighbor. (向右借资料)
+	TraceMerge                              // Two neighbor nodes are merged into one. (合拼)
+	TraceRootPromotion                      // The root is split and the tree grows by one level. (层数增加)
+	TraceRootCollapse                       // The root is replaced by its only child and the tree shrinks by one level. (层数减少)
+	TraceMask                               // An item is masked instead of being removed.
+)
+
+// String returns the name of the kind, so that the events can be logged directly.
+func (kind TraceKind) String() string {
+	switch kind {
+	case TraceSplit:
+		return "split"
+	case TraceBorrowLeft:
+		return "borrow-left"
+	case TraceBorrowRight:
+		return "borrow-right"
+	case TraceMerge:
+		return "merge"
+	case TraceRootPromotion:
+		return "root-promotion"
+	case TraceRootCollapse:
+		return "root-collapse"
+	case TraceMask:
+		return "mask"
+	}
+	return "unknown"
+}
+
+// TraceEventG describes one structural change of B plus tree.
+// Keys are the keys of the node after the change: the items of a data node, or the index of an index node.
+// The slice is a copy, the tracer may keep it.
+type TraceEventG[K any] struct {
+	Kind TraceKind // The kind of the change.
+	Data bool      // Keys come from a data node instead of an index node.
+	Keys []K       // The keys of the node after the change.
+}
+
+// TraceEventG with int64 keys.
+type TraceEvent = TraceEventG[int64]
+
+// TracerG receives the structural changes of B plus tree, so that they can be logged or counted.
+// Trace is called while the tree holds its lock, it must not call back into the tree.
+// (接收结构变化事件，在锁内呼叫，不可再操作这棵树)
+type TracerG[K any] interface {
+	Trace(event TraceEventG[K])
+}
+
+// TracerG with int64 keys.
+type Tracer = TracerG[int64]
+
+// SetTracer ensures thread safety, sets the tracer of B plus tree, release lock.
+// A nil tracer turns tracing off, which is the default.
+func (tree *BpTreeG[K, V]) SetTracer(tracer TracerG[K]) {
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Every node reaches the tracer through the config.
+	tree.cfg.tracer = tracer
+}
+
+// trace sends an event with a copy of the keys to the tracer, it does nothing without a tracer.
+func (cfg *bpConfig[K]) trace(kind TraceKind, data bool, keys []K) {
+	if cfg.tracer == nil {
+		return
+	}
+	cfg.tracer.Trace(TraceEventG[K]{Kind: kind, Data: data, Keys: append([]K{}, keys...)})
+}
+
+// trace sends an event with the keys of the data node.
+func (data *BpDataG[K, V]) trace(cfg *bpConfig[K], kind TraceKind) {
+	if cfg.tracer == nil {
+		return
+	}
+	items := data.items()
+	keys := make([]K, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	cfg.tracer.Trace(TraceEventG[K]{Kind: kind, Data: true, Keys: keys})
+}
+
+// trace sends an event with the index of the index node.
+func (inode *BpIndexG[K, V]) trace(cfg *bpConfig[K], kind TraceKind) {
+	cfg.trace(kind, false, inode.Index)
+}
@@ -1,205 +1,359 @@
 package bpTree
 
 import (
-	"fmt"
-	"sync"
+	"cmp"
+	"encoding/binary"
+	"time"
 )
 
-// The width and half-width for B plus tree.
-var (
-	BpWidth     int // the width of B plus tree.
-	BpHalfWidth int // the half-width of B plus tree.
-)
+// BpTreeG is the root of Tree B plus, K is the type of the key and V is the type of the value.
+type BpTreeG[K, V any] struct {
+	mutex   bpMutex[K, V]   // lock, lookups and scans share the read lock, modifications take the write lock
+	root    *BpIndexG[K, V] // root tree
+	version uint64          // modification count, iterators use it to notice changes
+	cfg     *bpConfig[K]    // settings shared by every node of this tree
+	gen     uint64          // copy-on-write generation, the nodes of older generations are shared with snapshots
+	file    *bpFile[K, V]   // the file of the tree, nil when the tree lives only in memory; set by Open
+	codec   treeCodec[K, V] // the codecs of the keys and the values; set by WithKeyCodec and WithValueCodec
+}
+
+// BpTree is B plus tree with int64 keys, it is the thin instantiation of BpTreeG that the package started with.
+type BpTree = BpTreeG[int64, any]
+
+// bpConfig carries the settings of one tree, and it is passed into the operations of BpIndex and BpData.
+// Every tree owns its config, so trees with different widths can live in the same process.
+// (同一棵树的节点共用设定，每棵树的宽度互不影响)
+type bpConfig[K any] struct {
+	width     int              // the width of B plus tree, a node splits when it reaches the width.
+	halfWidth int              // the half-width of B plus tree, the number of entries moved into the new node on a split.
+	compare   func(a, b K) int // Negative when a < b, zero when a == b and positive when a > b.
+	unique    bool             // Every key appears at most once; set by WithUniqueKeys.
+	cow       bool             // Modifications clone the shared nodes; set by WithCopyOnWrite.
+	lazy      bool             // Deletions mask the items instead of removing them; set by WithLazyDeletion.
+	tracer    TracerG[K]       // Receives the structural changes, nil when tracing is off; set by SetTracer.
+}
+
+// minItems returns the fewest items a data node below the root holds; a split leaves at least that many on each side.
+func (cfg *bpConfig[K]) minItems() int {
+	return cfg.width - cfg.halfWidth
+}
+
+// minIndex returns the fewest index keys an index node below the root holds; a split leaves at least that many on each side.
+func (cfg *bpConfig[K]) minIndex() int {
+	return cfg.halfWidth - 1
+}
 
-// BpTree is the root of Tree B plus.
-type BpTree struct {
-	mutex sync.Mutex // lock
-	root  *BpIndex   // root tree
+// BpOption configures B plus tree when it is created.
+type BpOption func(opts *bpOptions)
+
+// bpOptions collects the options before they are copied into bpConfig.
+type bpOptions struct {
+	unique bool // Reject duplicate keys.
+	cow    bool // Clone the nodes before changing them.
+	lazy   bool // Mask the deleted items.
+
+	order    binary.ByteOrder // The byte order of a new file.
+	pageSize int              // The page size of a new file.
+
+	wal          *SyncPolicy   // The policy of the write-ahead log, nil without one.
+	syncInterval time.Duration // The interval of SyncInterval.
+	poolPages    int           // The capacity of the buffer pool, zero sizes it from the available memory.
+
+	keyCodec any // The Codec of the keys.
+	valCodec any // The Codec of the values.
+}
+
+// WithUniqueKeys makes every key appear at most once. Insert rejects an existing key with ErrDuplicateKey,
+// use Upsert to replace the value instead.
+// (唯一键模式)
+func WithUniqueKeys() BpOption {
+	return func(opts *bpOptions) {
+		opts.unique = true
+	}
+}
+
+// WithDuplicates lets Insert store the same key several times. It is the default mode.
+// (允许重复键，为预设模式)
+func WithDuplicates() BpOption {
+	return func(opts *bpOptions) {
+		opts.unique = false
+	}
 }
 
 // NewBpTree initializes B plus tree structure with specified width and data entries.
-func NewBpTree(width int) (tree *BpTree) {
+func NewBpTree(width int, opts ...BpOption) (tree *BpTree) {
+	return NewBpTreeG[int64, any](width, opts...)
+}
+
+// NewBpTreeG initializes B plus tree whose keys are ordered by the < operator.
+func NewBpTreeG[K cmp.Ordered, V any](width int, opts ...BpOption) (tree *BpTreeG[K, V]) {
+	return NewBpTreeFunc[K, V](width, cmp.Compare[K], opts...)
+}
+
+// NewBpTreeFunc initializes B plus tree whose keys are ordered by the compare function.
+// The compare function returns a negative number when a < b, zero when a == b and a positive number when a > b,
+// the same as cmp.Compare and bytes.Compare.
+func NewBpTreeFunc[K, V any](width int, compare func(a, b K) int, opts ...BpOption) (tree *BpTreeG[K, V]) {
+	// Apply the options.
+	var options bpOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// Set the width and half-width for B plus tree.
 	if width < 3 { // The minimum width for B plus tree is 3.
 		width = 3
 	}
-	BpWidth = width
-	BpHalfWidth = int((float32(BpWidth)-0.1)/2) + 1
+	cfg := &bpConfig[K]{
+		width:     width,
+		halfWidth: int((float32(width)-0.1)/2) + 1,
+		compare:   compare,
+		unique:    options.unique,
+		cow:       options.cow,
+		lazy:      options.lazy,
+	}
 
 	// Create root tree instance
-	tree = &BpTree{
-		root: &BpIndex{
-			DataNodes: make([]*BpData, 0, BpWidth+1), // The addition of 1 is because data chunks may temporarily exceed the width.
+	tree = &BpTreeG[K, V]{
+		root: &BpIndexG[K, V]{
+			DataNodes: make([]*BpDataG[K, V], 0, cfg.width+1), // The addition of 1 is because data chunks may temporarily exceed the width.
 		},
+		cfg:   cfg,
+		codec: newTreeCodec[K, V](options, options.order),
 	}
 
 	// Prepare one data slice first; one data slice will not generate an index.
-	tree.root.DataNodes = append(tree.root.DataNodes, &BpData{})
+	tree.root.DataNodes = append(tree.root.DataNodes, &BpDataG[K, V]{})
 
 	return
 }
 
 // InsertValue ensures thread safety, insert item in B plus tree index, release lock.
-func (tree *BpTree) InsertValue(item BpItem) {
+// It panics when the insertion fails, use Insert to get the error instead.
+func (tree *BpTreeG[K, V]) InsertValue(item BpItemG[K, V]) {
+	if err := tree.Insert(item); err != nil {
+		panic(err)
+	}
+}
+
+// Insert ensures thread safety, insert item in B plus tree index, release lock.
+// When the index is corrupted, it returns an error matching ErrIndexCorrupted and the tree is left unchanged.
+// With WithUniqueKeys, an existing key is rejected with ErrDuplicateKey.
+func (tree *BpTreeG[K, V]) Insert(item BpItemG[K, V]) (err error) {
+	// With a write-ahead log, wait for the record after the lock is released, so the writers share the syncing.
+	var wait walWait
+	defer wait.wait(&err)
+
 	// Acquire a lock to ensure thread safety.
 	tree.mutex.Lock()
 
-	// Insert the item into the B plus tree index.
-	_, popKey, popNode, status, err := tree.root.insertItem(nil, item)
+	// Release the lock to allow other threads to access the tree, even when the insertion fails.
+	defer tree.mutex.Unlock()
 
-	if err != nil {
-		panic(err)
+	// In unique mode, the key must not exist yet. (唯一键模式先检查)
+	if tree.cfg.unique {
+		if _, found := tree.root.search(tree.cfg, item.Key); found {
+			err = ErrDuplicateKey
+			return
+		}
 	}
 
-	if status == statusProtrudeInode && popNode != nil {
-		// Here, it will increase the entire tree's depth. (层数增加)
-		tree.root = popNode
-		status = statusNormal
+	// Log the insertion before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walInsert, key: item.Key, val: item.Val}); err != nil {
+		return
 	}
 
-	if status == statusProtrudeDnode {
-		err = tree.root.mergeWithDnode(popKey, popNode)
-		status = statusNormal
-		if err != nil {
-			return
-		}
+	// Performing the insertion.
+	err = tree.insert(item)
+
+	// Performing a return.
+	return
+}
+
+// Upsert sets the value of the key and returns the old value, or inserts a new item when the key does not exist.
+// With duplicates, it replaces the value of the item Get returns.
+// When the insertion or the write-ahead log fails, it returns the error and the tree is left unchanged.
+func (tree *BpTreeG[K, V]) Upsert(key K, val V) (old V, replaced bool, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
+
+	// Log the change before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walUpsert, key: key, val: val}); err != nil {
+		return
 	}
 
-	if len(tree.root.Index) >= BpWidth && len(tree.root.Index)%2 != 0 {
-		popNode, _ = tree.root.protrudeInOddBpWidth()
-		tree.root = popNode
-	} else if len(tree.root.Index) >= BpWidth && len(tree.root.Index)%2 == 0 {
-		popNode, _ = tree.root.protrudeInEvenBpWidth()
-		tree.root = popNode
+	// Performing the upsert.
+	old, replaced, err = tree.upsert(key, val)
+
+	// Performing a return.
+	return
+}
+
+// upsert sets the value of the key or inserts a new item, the caller holds the lock.
+func (tree *BpTreeG[K, V]) upsert(key K, val V) (old V, replaced bool, err error) {
+	// Replace the value in place when the key exists. (键存在时直接替换)
+	tree.own(key)
+	if data, ix, found := tree.root.locate(tree.cfg, key); found {
+		tree.version++
+		old, replaced = data.Items[ix].Val, true
+		data.Items[ix].Val = val
+		return
 	}
 
-	// Release the lock to allow other threads to access the tree.
-	tree.mutex.Unlock()
+	// Otherwise insert a new item.
+	err = tree.insert(BpItemG[K, V]{Key: key, Val: val})
 
 	// Performing a return.
 	return
 }
 
-// RemoveValue ensures thread safety, remove item in B plus tree index, release lock.
-func (tree *BpTree) RemoveValue(item BpItem) (deleted, updated bool, ix int, err error) {
+// InsertIfAbsent inserts the item only when no item with the same key exists, in either mode.
+func (tree *BpTreeG[K, V]) InsertIfAbsent(key K, val V) (inserted bool, err error) {
+	// With a write-ahead log, wait for the record after the lock is released.
+	var wait walWait
+	defer wait.wait(&err)
+
 	// Acquire a lock to ensure thread safety.
 	tree.mutex.Lock()
 
 	// Release the lock to allow other threads to access the tree.
 	defer tree.mutex.Unlock()
 
-	// The deletion operation is currently managed by the root node to prevent issues with mismatched levels of child nodes.
-	// If the levels of child nodes are not correct, the B plus tree may malfunction. ‼️
-	// 删除操作由根节点管理，确保所有子节点层级相同 ‼️
+	// Skip the existing key.
+	if _, found := tree.root.search(tree.cfg, key); found {
+		return
+	}
 
-	// Performing deletion operation.
-	var edgeValue int64 = -1
-	deleted, updated, ix, edgeValue, err = tree.root.delFromRoot(item)
-
-	// 以下进行临时修正
-	if ix >= 0 && ix <= len(tree.root.IndexNodes)-1 && len(tree.root.IndexNodes[ix].Index) == 0 {
-		// if item.Key == 537 {
-		// fmt.Println(">>>>> 暂时的修正")
-		err = tree.root.borrowFromRootIndexNode(ix, edgeValue)
-		// tree.root.Index = []int64{1383} // 已修正完成
-		// tree.root.IndexNodes[0].Index = []int64{229, 553}
-		// tree.root.IndexNodes[1].Index = []int64{1633} // 已修正完成
-		// }
+	// Log the insertion before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walInsert, key: key, val: val}); err != nil {
 		return
 	}
 
-	// ⚠️ The following is the B plus tree merging operation.
-	// The merging criteria here do not rely on an empty node index. ‼️
-	// This is done to increase the chances of merging, as the index may not be cleared on time.
-	// 这里不以节点 index 为空为合拼标准，因为 index 可能没有及时清空
+	// Performing the insertion.
+	if err = tree.insert(BpItemG[K, V]{Key: key, Val: val}); err == nil {
+		inserted = true
+	}
+
+	// Performing a return.
+	return
+}
+
+// insert puts the item into B plus tree index, the caller holds the lock.
+func (tree *BpTreeG[K, V]) insert(item BpItemG[K, V]) (err error) {
+	// In copy-on-write mode, clone the nodes shared with snapshots first.
+	tree.own(item.Key)
 
-	if len(tree.root.Index) == 0 && len(tree.root.IndexNodes) == 1 {
-		tree.root = tree.root.IndexNodes[0]
+	// In lazy deletion mode with unique keys, a masked item of the key is used again. (重复使用被遮罩的资料)
+	// With duplicates, the new item goes after the existing ones instead, to keep the insertion order.
+	if tree.cfg.lazy && tree.cfg.unique && tree.revive(item) {
+		tree.version++
 		return
 	}
 
-	if len(tree.root.Index) == 1 && len(tree.root.IndexNodes) == 2 && ix >= 0 && ix < len(tree.root.IndexNodes)-1 && len(tree.root.IndexNodes[ix].IndexNodes) == 1 {
-		// 当根结点其中一个分支利一个索引值和一个索引节点，这个分支就要和其他分支进行全拼
-		tree.root.IndexNodes[ix].Index = []int64{}
-		if ix == 0 {
-			node := &BpIndex{}
-			node.Index = append([]int64{tree.root.IndexNodes[1].edgeValue()}, tree.root.IndexNodes[1].Index...)
-			node.IndexNodes = append(tree.root.IndexNodes[0].IndexNodes, tree.root.IndexNodes[1].IndexNodes...)
-			*tree.root = *node
-			return
-		} else if ix == 1 {
-			fmt.Println("这里还没写完")
-		}
+	// Insert the item into the B plus tree index.
+	_, popKey, popNode, status, err := tree.root.insertItem(tree.cfg, nil, item)
+	if err != nil {
+		return
 	}
 
-	// ⚠️ When there is only one remaining index child node. (索引节点的升级合拼)
-	if len(tree.root.IndexNodes) == 1 && len(tree.root.DataNodes) == 0 {
-		*tree.root = *tree.root.IndexNodes[0]
-		return
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	if status == statusProtrudeInode && popNode != nil {
+		// Here, it will increase the entire tree's depth. (层数增加)
+		tree.root = popNode
+		tree.root.trace(tree.cfg, TraceRootPromotion)
+		status = statusNormal
 	}
 
-	// ⚠️ When there is only two remaining data child nodes. (资料节点的升级合拼)
-	if len(tree.root.DataNodes) == 2 && len(tree.root.IndexNodes) == 0 {
-		// If one of the data nodes indeed has no data.
-		if len(tree.root.DataNodes[0].Items) == 0 && len(tree.root.DataNodes[1].Items) != 0 {
-			// If the first data node is empty, replace the root node with the second data node.
-			tree.root.Index = nil
-			tree.root.DataNodes = []*BpData{tree.root.DataNodes[1]}
-			return
-		} else if len(tree.root.DataNodes[1].Items) == 0 && len(tree.root.DataNodes[0].Items) != 0 {
-			// If the second data node is empty, replace the root node with the first data node.
-			tree.root.Index = nil
-			tree.root.DataNodes = []*BpData{tree.root.DataNodes[0]}
+	if status == statusProtrudeDnode {
+		if err = tree.root.mergeWithDnode(popKey, popNode); err != nil {
 			return
 		}
+		tree.root.trace(tree.cfg, TraceRootPromotion)
+		status = statusNormal
 	}
 
-	// ⚠️ If there are only 2 index nodes, but the data is not a lot, they can be merged.
-	if len(tree.root.IndexNodes) == 2 &&
-		BpWidth > (len(tree.root.IndexNodes[0].Index)+len(tree.root.IndexNodes[1].Index)) { // Combine within the range of BpWidth.
+	if len(tree.root.Index) >= tree.cfg.width && len(tree.root.Index)%2 != 0 {
+		if popNode, err = tree.root.protrudeInOddBpWidth(); err != nil {
+			return
+		}
+		tree.root = popNode
+		tree.root.trace(tree.cfg, TraceRootPromotion)
+	} else if len(tree.root.Index) >= tree.cfg.width && len(tree.root.Index)%2 == 0 {
+		if popNode, err = tree.root.protrudeInEvenBpWidth(); err != nil {
+			return
+		}
+		tree.root = popNode
+		tree.root.trace(tree.cfg, TraceRootPromotion)
+	}
 
-		// Begin the merger process.
-		if len(tree.root.Index) == 0 && len(tree.root.IndexNodes) > 0 {
-			// Create a new BpIndex node.
-			node := &BpIndex{}
+	// Renew the item counts along the path of the key.
+	tree.root.recount(tree.cfg, item.Key)
 
-			// Merge all index nodes into a new node.
-			for i := 0; i < len(tree.root.IndexNodes); i++ {
-				node.Index = append(node.Index, tree.root.IndexNodes[i].Index...)
-				node.IndexNodes = append(node.IndexNodes, tree.root.IndexNodes[i].IndexNodes...)
-				node.DataNodes = append(node.DataNodes, tree.root.IndexNodes[i].DataNodes...)
-			}
+	// Performing a return.
+	return
+}
 
-			// Replace the original root node with the new node.
-			*tree.root = *node
-		}
-	}
+// RemoveValue ensures thread safety, remove item in B plus tree index, release lock.
+// With WithUniqueKeys it removes the only item of the key, with duplicates it removes the newest item of the key.
+// The value of the item is not looked at, RemoveExact and RemoveIf choose the items by their values.
+func (tree *BpTreeG[K, V]) RemoveValue(item BpItemG[K, V]) (deleted, updated bool, ix int, err error) {
+	// With a write-ahead log, wait for the record after the lock is released, so the writers share the syncing.
+	var wait walWait
+	defer wait.wait(&err)
 
-	// ⚠️ Warning: The following code appears to perform a restructuring operation on a B Plus tree.
-	// 当根节点直接连接到资料节点，而且分支数量只有 2 个的时候，这时根节点规模会过小
-	if len(tree.root.DataNodes) == 2 &&
-		BpWidth > (len(tree.root.DataNodes[0].Items)+len(tree.root.DataNodes[1].Items)) {
+	// Acquire a lock to ensure thread safety.
+	tree.mutex.Lock()
 
-		// Create a new BpIndex node.
-		node := &BpIndex{}
-		node.DataNodes = append(node.DataNodes, &BpData{})
-		node.DataNodes[0].Items = append(node.DataNodes[0].Items, tree.root.DataNodes[0].Items...)
-		node.DataNodes[0].Items = append(node.DataNodes[0].Items, tree.root.DataNodes[1].Items...)
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.Unlock()
 
-		// Replace the original root node with the new node.
-		*tree.root = *node
+	// Log the deletion before applying it.
+	if wait, err = tree.file.logRecord(walRecord[K, V]{kind: walRemove, key: item.Key}); err != nil {
+		return
 	}
 
+	// Every modification invalidates the positions held by iterators.
+	tree.version++
+
+	// Performing deletion operation.
+	deleted, updated, ix, err = tree.delAndDir(item)
+
 	// Performing a return.
 	return
 }
 
+// renewKeys sets the index keys of the node to the edge values of its children, the empty children are skipped.
+func (inode *BpIndexG[K, V]) renewKeys() {
+	for i := 1; i < len(inode.IndexNodes) && i-1 < len(inode.Index); i++ {
+		if items := inode.IndexNodes[i].BpDataHead().items(); len(items) > 0 {
+			inode.Index[i-1] = items[0].Key
+		}
+	}
+	for i := 1; i < len(inode.DataNodes) && i-1 < len(inode.Index); i++ {
+		if items := inode.DataNodes[i].items(); len(items) > 0 {
+			inode.Index[i-1] = items[0].Key
+		}
+	}
+}
+
 // edgeValue 是用来计算索引节点节点的边界值
-func (inode *BpIndex) edgeValue() int64 {
+// It returns the zero value of K when the node holds no data.
+func (inode *BpIndexG[K, V]) edgeValue() (key K) {
 	if len(inode.IndexNodes) > 0 {
 		return inode.IndexNodes[0].edgeValue()
-	} else if len(inode.DataNodes) > 0 && len(inode.DataNodes[0].Items) > 0 {
-		return inode.DataNodes[0].Items[0].Key
+	} else if len(inode.DataNodes) > 0 {
+		if items := inode.DataNodes[0].items(); len(items) > 0 {
+			return items[0].Key
+		}
 	}
-	return -1
+	return
 }
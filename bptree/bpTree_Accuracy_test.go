@@ -1,3 +1,5 @@
+//go:build linux
+
 package bpTree
 
 // =====================================================================================================================
@@ -17,7 +19,6 @@ package bpTree
 // =====================================================================================================================
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
@@ -94,15 +95,3 @@ func Test_Check_BpTree_Accuracies(t *testing.T) {
 		runMode3(t)
 	})
 }
-
-// shuffleSlice randomly shuffles the elements in the slice.
-func shuffleSlice(slice []int64, rng *rand.Rand) {
-	// Iterate through the slice in reverse order, starting from the last element.
-	for i := len(slice) - 1; i > 0; i-- {
-		// Generate a random index 'j' between 0 and i (inclusive).
-		j := rng.Intn(i + 1)
-
-		// Swap the elements at indices i and j.
-		slice[i], slice[j] = slice[j], slice[i]
-	}
-}
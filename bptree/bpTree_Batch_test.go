@@ -0,0 +1,165 @@
+package bpTree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signedStream creates a signed stream like the test models do: every key is inserted once and deleted once,
+// and a deletion always comes after the insertion of its key.
+func signedStream(rng *rand.Rand, count int) (stream []int64) {
+	keys := rng.Perm(count)
+	var pending []int64
+	for len(keys) > 0 || len(pending) > 0 {
+		if len(keys) > 0 && (len(pending) == 0 || rng.Intn(3) > 0) {
+			key := int64(keys[0]) + 1
+			keys = keys[1:]
+			stream = append(stream, key)
+			pending = append(pending, key)
+			continue
+		}
+		i := rng.Intn(len(pending))
+		stream = append(stream, -pending[i])
+		pending = slices.Delete(pending, i, i+1)
+	}
+	return
+}
+
+// Test_BpTree_ApplyBatch 🧫 replays signed streams in batches and compares the result with one-by-one operations.
+func Test_BpTree_ApplyBatch(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 16} {
+		for _, batchSize := range []int{1, 7, 100, 5000} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width*10000 + batchSize)))
+			stream := signedStream(rng, 2000)
+
+			tree := NewBpTree(width)
+			reference := NewBpTree(width)
+			for from := 0; from < len(stream); from += batchSize {
+				batch := stream[from:min(from+batchSize, len(stream))]
+
+				// The batch reports every operation of the stream as applied.
+				inserted, deleted, err := tree.ApplyBatch(OpsFromSigned(batch))
+				require.NoError(t, err)
+				require.Equal(t, len(batch), inserted+deleted)
+
+				// The reference applies them one by one.
+				for _, value := range batch {
+					if value >= 0 {
+						reference.InsertValue(BpItem{Key: value})
+					} else {
+						reference.RemoveValue(BpItem{Key: -value})
+					}
+				}
+
+				require.NoError(t, tree.Validate(), "width %d, batch size %d, from %d", width, batchSize, from)
+				require.Equal(t, snapshotKeys(reference), snapshotKeys(tree))
+			}
+			require.Empty(t, snapshotKeys(tree))
+		}
+	}
+}
+
+// Test_BpTree_ApplyBatch_Order 🧫 checks that the operations on the same key keep their order,
+// and that the skipped operations are not counted.
+func Test_BpTree_ApplyBatch_Order(t *testing.T) {
+	// Duplicates: insert twice, delete three times, the last deletion finds nothing.
+	tree := NewBpTree(4)
+	inserted, deleted, err := tree.ApplyBatch([]Op{
+		{Kind: OpInsert, Item: BpItem{Key: 5}},
+		{Kind: OpDelete, Item: BpItem{Key: 5}},
+		{Kind: OpInsert, Item: BpItem{Key: 3}},
+		{Kind: OpInsert, Item: BpItem{Key: 5}},
+		{Kind: OpInsert, Item: BpItem{Key: 5}},
+		{Kind: OpDelete, Item: BpItem{Key: 5}},
+		{Kind: OpDelete, Item: BpItem{Key: 9}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4, inserted)
+	require.Equal(t, 2, deleted)
+	require.Equal(t, []int64{3, 5}, snapshotKeys(tree))
+
+	// Unique keys: the second insertion of a key is skipped.
+	tree = NewBpTree(4, WithUniqueKeys())
+	ops := []Op{
+		{Kind: OpInsert, Item: BpItem{Key: 1, Val: "first"}},
+		{Kind: OpInsert, Item: BpItem{Key: 1, Val: "second"}},
+		{Kind: OpInsert, Item: BpItem{Key: 2}},
+	}
+	inserted, deleted, err = tree.ApplyBatch(ops)
+	require.NoError(t, err)
+	require.Equal(t, 2, inserted)
+	require.Zero(t, deleted)
+	item, _ := tree.Get(1)
+	require.Equal(t, "first", item.Val)
+
+	// The slice of the caller is not sorted in place.
+	require.Equal(t, "second", ops[1].Item.Val)
+}
+
+// Test_BpTree_ApplyBatch_Duplicates 🧫 applies random batches on a few duplicate keys and compares the values
+// with the same operations applied one by one, so every deletion must remove the same duplicate.
+func Test_BpTree_ApplyBatch_Duplicates(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 8} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(int64(width)))
+		tree := NewBpTree(width, WithDuplicates())
+		reference := NewBpTree(width, WithDuplicates())
+		for round := 0; round < 50; round++ {
+			ops := make([]Op, 1+rng.Intn(40))
+			for i := range ops {
+				ops[i] = Op{Kind: OpInsert, Item: BpItem{Key: rng.Int63n(6), Val: round*100 + i}}
+				if rng.Intn(5) < 2 {
+					ops[i].Kind = OpDelete
+				}
+			}
+			_, _, err := tree.ApplyBatch(ops)
+			require.NoError(t, err)
+
+			// The reference applies them one by one, in key order with the operations on a key kept in order.
+			slices.SortStableFunc(ops, func(a, b Op) int {
+				return int(a.Item.Key - b.Item.Key)
+			})
+			for _, op := range ops {
+				if op.Kind == OpInsert {
+					require.NoError(t, reference.Insert(op.Item))
+				} else {
+					reference.RemoveValue(op.Item)
+				}
+			}
+
+			require.NoError(t, tree.Validate(), "width %d, round %d", width, round)
+			require.Equal(t, reference.Range(0, 6), tree.Range(0, 6), "width %d, round %d", width, round)
+		}
+	}
+}
+
+// Benchmark_BpTree_ApplyBatch compares replaying a signed stream in batches with one-by-one operations.
+func Benchmark_BpTree_ApplyBatch(b *testing.B) {
+	stream := signedStream(rand.New(rand.NewSource(1)), 100000)
+
+	b.Run("OneByOne", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree := NewBpTree(32)
+			for _, value := range stream {
+				if value >= 0 {
+					tree.InsertValue(BpItem{Key: value})
+				} else {
+					tree.RemoveValue(BpItem{Key: -value})
+				}
+			}
+		}
+	})
+
+	b.Run("Batch1000", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree := NewBpTree(32)
+			for from := 0; from < len(stream); from += 1000 {
+				_, _, _ = tree.ApplyBatch(OpsFromSigned(stream[from:min(from+1000, len(stream))]))
+			}
+		}
+	})
+}
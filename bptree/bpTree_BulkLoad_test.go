@@ -0,0 +1,113 @@
+package bpTree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/panhongrainbow/go-algorithm/randhub"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_BulkLoad 🧫 bulk loads sorted keys with several widths, sizes and fill factors,
+// then keeps inserting and removing on the loaded tree.
+func Test_BpTree_BulkLoad(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 16} {
+		for _, size := range []int{0, 1, 2, 3, 5, 17, 100, 1000} {
+			for _, fillFactor := range []float64{0.1, 0.5, 0.7, 1} {
+				// The sorted keys come from the number pool.
+				pool := randhub.NewNumberPool[int64]()
+				_, _, err := pool.GenerateUniqueNumbers(-5000, 5000, randhub.WithBasicOpt(size, 0, false))
+				require.NoError(t, err)
+				keys := pool.ExtractSortedKeys()
+
+				items := make([]BpItem, 0, len(keys))
+				for _, key := range keys {
+					items = append(items, BpItem{Key: key, Val: key})
+				}
+
+				// The loaded tree is sound and holds every key.
+				tree := NewBpTree(width, WithUniqueKeys())
+				require.NoError(t, tree.BulkLoad(items, fillFactor))
+				require.NoError(t, tree.Validate(), "width %d, size %d, fill factor %v", width, size, fillFactor)
+				require.Equal(t, len(keys), countSeq(tree))
+				for _, key := range keys {
+					item, found := tree.Get(key)
+					require.True(t, found)
+					require.Equal(t, key, item.Val)
+				}
+
+				// Insertions and deletions keep working afterward.
+				rng := rand.New(rand.NewSource(int64(size)))
+				for i := 0; i < 200; i++ {
+					key := rng.Int63n(10000) - 5000
+					if err := tree.Insert(BpItem{Key: key}); err == nil {
+						keys = append(keys, key)
+					}
+				}
+				shuffleSlice(keys, rng)
+				for _, key := range keys {
+					deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+					require.True(t, deleted)
+					require.NoError(t, err)
+					require.NoError(t, tree.Validate())
+				}
+			}
+		}
+	}
+}
+
+// Test_BpTree_BulkLoad_Duplicates 🧫 bulk loads keys that repeat across data nodes.
+func Test_BpTree_BulkLoad_Duplicates(t *testing.T) {
+	for _, width := range []int{3, 4, 6} {
+		var keys []int64
+		for key := int64(0); key < 50; key++ {
+			for n := key % 5; n >= 0; n-- {
+				keys = append(keys, key)
+			}
+		}
+
+		// Duplicates are kept in the default mode.
+		tree := NewBpTree(width)
+		require.NoError(t, tree.BulkLoadSeq(func(yield func(int64, any) bool) {
+			for _, key := range keys {
+				if !yield(key, nil) {
+					return
+				}
+			}
+		}, 1))
+		require.NoError(t, tree.Validate())
+		require.Equal(t, keys, snapshotKeys(tree))
+
+		// Every duplicate can be removed.
+		rng := rand.New(rand.NewSource(int64(width)))
+		shuffled := slices.Clone(keys)
+		shuffleSlice(shuffled, rng)
+		for _, key := range shuffled {
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+			require.True(t, deleted)
+			require.NoError(t, err)
+			require.NoError(t, tree.Validate())
+		}
+	}
+}
+
+// Test_BpTree_BulkLoad_Rejected 🧫 checks that invalid input is rejected and the tree is left unchanged.
+func Test_BpTree_BulkLoad_Rejected(t *testing.T) {
+	tree := NewBpTree(4, WithUniqueKeys())
+	for key := int64(0); key < 10; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key}))
+	}
+	before := snapshotKeys(tree)
+
+	require.ErrorIs(t, tree.BulkLoad([]BpItem{{Key: 1}, {Key: 3}, {Key: 2}}, 1), ErrNotSorted)
+	require.ErrorIs(t, tree.BulkLoad([]BpItem{{Key: 1}, {Key: 1}}, 1), ErrDuplicateKey)
+	require.ErrorIs(t, tree.BulkLoad([]BpItem{{Key: 1}}, 0), ErrInvalidFillFactor)
+	require.ErrorIs(t, tree.BulkLoad([]BpItem{{Key: 1}}, 1.5), ErrInvalidFillFactor)
+	require.Equal(t, before, snapshotKeys(tree))
+	require.NoError(t, tree.Validate())
+
+	// The valid input replaces the content.
+	require.NoError(t, tree.BulkLoad([]BpItem{{Key: 100}, {Key: 200}}, 1))
+	require.Equal(t, []int64{100, 200}, snapshotKeys(tree))
+}
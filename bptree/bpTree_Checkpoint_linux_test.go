@@ -0,0 +1,29 @@
+//go:build linux
+
+package bpTree
+
+import (
+	"os"
+
+	"github.com/panhongrainbow/go-algorithm/utilhub"
+)
+
+// Linux compares Checkpoint with LinuxSpliceStreamWrite as well.
+func init() {
+	checkpointWriters = append(checkpointWriters, checkpointWriter{"LinuxSpliceStreamWrite", spliceWrite})
+}
+
+// spliceWrite writes through LinuxSpliceStreamWrite in chunks of 32 KiB, it syncs the file system when it finishes.
+func spliceWrite(path string, size int64) (err error) {
+	dataChan, finishChan, err := utilhub.LinuxSpliceStreamWrite(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermission)
+	if err != nil {
+		return
+	}
+	chunk := make([]byte, 32<<10)
+	for sent := int64(0); sent < size; sent += int64(len(chunk)) {
+		dataChan <- [][]byte{chunk[:min(int64(len(chunk)), size-sent)]}
+	}
+	close(dataChan)
+	<-finishChan
+	return
+}
@@ -0,0 +1,345 @@
+package bpTree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Checkpoint 🧫 dumps trees with duplicates, masked items and a value larger than a pipe,
+// loads the dumps into new trees, and compares the items.
+func Test_BpTree_Checkpoint(t *testing.T) {
+	for _, opts := range [][]BpOption{{WithUniqueKeys()}, {WithDuplicates(), WithLazyDeletion()}} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(1))
+		tree := NewBpTree(5, opts...)
+		values := []any{nil, int64(-7), 42, 3.5, true, "row", []byte{1, 2, 3}}
+		for i := 0; i < 5000; i++ {
+			item := BpItem{Key: rng.Int63n(3000), Val: values[rng.Intn(len(values))]}
+			if rng.Intn(4) > 0 {
+				_ = tree.Insert(item)
+			} else {
+				_, _, _, _ = tree.RemoveValue(item)
+			}
+		}
+		_ = tree.Insert(BpItem{Key: 3000, Val: make([]byte, 100<<10)})
+
+		path := filepath.Join(t.TempDir(), "tree.ck")
+		manifest, err := tree.Checkpoint(path)
+		require.NoError(t, err)
+		require.Equal(t, uint64(tree.Len()), manifest.Items)
+		require.Equal(t, "tree.ck.1", manifest.Dump)
+		require.Empty(t, manifest.Segment)
+		info, err := os.Stat(filepath.Join(filepath.Dir(path), manifest.Dump))
+		require.NoError(t, err)
+		require.Equal(t, manifest.Size, info.Size())
+
+		// The dump reloads into a tree of another width, the masked items are left out.
+		loaded := NewBpTree(4, opts...)
+		require.NoError(t, loaded.Insert(BpItem{Key: -1}))
+		read, err := loaded.LoadCheckpoint(path, 0.7)
+		require.NoError(t, err)
+		require.Equal(t, manifest, read)
+		require.NoError(t, loaded.Validate())
+		require.Zero(t, loaded.Masked())
+		require.Equal(t, collectItems(tree), collectItems(loaded))
+	}
+
+	// An empty tree makes an empty dump.
+	path := filepath.Join(t.TempDir(), "empty.ck")
+	_, err := NewBpTree(4).Checkpoint(path)
+	require.NoError(t, err)
+	loaded := NewBpTree(4)
+	require.NoError(t, loaded.Insert(BpItem{Key: 1}))
+	_, err = loaded.LoadCheckpoint(path, 1)
+	require.NoError(t, err)
+	require.Zero(t, loaded.Len())
+}
+
+// Test_BpTree_Checkpoint_WAL 🧫 checks that the manifest records the segment and the offset the checkpoint covers.
+func Test_BpTree_Checkpoint_WAL(t *testing.T) {
+	dir := t.TempDir()
+	tree, err := Open(filepath.Join(dir, "tree.bp"), 4, WithByteOrder(binary.BigEndian), WithWriteAheadLog(SyncNever, 0))
+	require.NoError(t, err)
+	for key := int64(0); key < 100; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: strconv.Itoa(int(key))}))
+	}
+	require.NoError(t, tree.Flush())
+	for key := int64(100); key < 150; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: strconv.Itoa(int(key))}))
+	}
+
+	// The checkpoint covers the segment of the last Flush up to the records so far.
+	manifest, err := tree.Checkpoint(filepath.Join(dir, "tree.ck"))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Base(segmentPath(filepath.Join(dir, "tree.bp"), tree.file.header.seq)), manifest.Segment)
+	info, err := os.Stat(filepath.Join(dir, manifest.Segment))
+	require.NoError(t, err)
+	require.Equal(t, info.Size(), manifest.Offset)
+
+	// The records after the checkpoint are appended past the offset.
+	require.NoError(t, tree.Insert(BpItem{Key: 1000}))
+	info, err = os.Stat(filepath.Join(dir, manifest.Segment))
+	require.NoError(t, err)
+	require.Greater(t, info.Size(), manifest.Offset)
+
+	// The dump does not depend on the byte order of the file.
+	loaded := NewBpTree(3)
+	_, err = loaded.LoadCheckpoint(filepath.Join(dir, "tree.ck"), 1)
+	require.NoError(t, err)
+	require.Equal(t, 150, loaded.Len())
+	require.NoError(t, tree.Close())
+}
+
+// Test_BpTree_Checkpoint_Generations 🧫 checkpoints to the same path again, every dump gets a name of its own,
+// and a dump without its manifest, as a crash leaves it, does not replace the last checkpoint.
+func Test_BpTree_Checkpoint_Generations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.ck")
+	tree := NewBpTree(4)
+	for generation := 1; generation <= 3; generation++ {
+		require.NoError(t, tree.Insert(BpItem{Key: int64(generation)}))
+		manifest, err := tree.Checkpoint(path)
+		require.NoError(t, err)
+		require.Equal(t, "tree.ck."+strconv.Itoa(generation), manifest.Dump)
+
+		// The dump of the checkpoint before is removed.
+		dumps, err := filepath.Glob(path + ".[0-9]*")
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(dir, manifest.Dump)}, dumps)
+	}
+
+	// A crash after the dump of the next checkpoint, before its manifest.
+	require.NoError(t, os.WriteFile(path+".4", []byte("torn"), filePermission))
+	loaded := NewBpTree(4)
+	manifest, err := loaded.LoadCheckpoint(path, 1)
+	require.NoError(t, err)
+	require.Equal(t, "tree.ck.3", manifest.Dump)
+	require.Equal(t, collectItems(tree), collectItems(loaded))
+
+	// The next checkpoint writes over it.
+	manifest, err = tree.Checkpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, "tree.ck.4", manifest.Dump)
+	_, err = loaded.LoadCheckpoint(path, 1)
+	require.NoError(t, err)
+}
+
+// Test_BpTree_Checkpoint_Recover 🧫 takes checkpoints of a tree with a write-ahead log between flushes, crashes it,
+// and checks that RecoverCheckpoint brings back the same items as Open. Flush keeps the segments
+// from the one of the last checkpoint on, and the next checkpoint removes the ones before its own.
+func Test_BpTree_Checkpoint_Recover(t *testing.T) {
+	for _, opts := range [][]BpOption{{WithUniqueKeys()}, {WithDuplicates(), WithLazyDeletion()}} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(1))
+		dir := t.TempDir()
+		path, ck := filepath.Join(dir, "tree.bp"), filepath.Join(dir, "tree.ck")
+		segments := func() (numbers []uint64) {
+			names, err := filepath.Glob(path + ".wal.*")
+			require.NoError(t, err)
+			for _, name := range names {
+				number, err := strconv.ParseUint(filepath.Ext(name)[1:], 10, 64)
+				require.NoError(t, err)
+				numbers = append(numbers, number)
+			}
+			slices.Sort(numbers)
+			return
+		}
+		change := func(tree *BpTree, n int) {
+			for i := 0; i < n; i++ {
+				item := BpItem{Key: rng.Int63n(300), Val: int64(i)}
+				switch op := rng.Intn(10); {
+				case op < 6:
+					if err := tree.Insert(item); !errors.Is(err, ErrDuplicateKey) {
+						require.NoError(t, err)
+					}
+				case op < 9:
+					_, _, _, err := tree.RemoveValue(item)
+					require.NoError(t, err)
+				default:
+					_, err := tree.DeleteRange(item.Key, item.Key+rng.Int63n(8))
+					require.NoError(t, err)
+				}
+			}
+		}
+
+		for round := 0; round < 3; round++ {
+			tree, err := Open(path, 4, append(opts, WithWriteAheadLog(SyncAlways, 0))...)
+			require.NoError(t, err)
+			change(tree, 200)
+			require.NoError(t, tree.Flush())
+			change(tree, 100)
+
+			// The checkpoint removes the segments before its own.
+			manifest, err := tree.Checkpoint(ck)
+			require.NoError(t, err)
+			first := tree.file.header.seq
+			require.Equal(t, filepath.Base(segmentPath(path, first)), manifest.Segment)
+			require.Equal(t, []uint64{first}, segments())
+
+			// Flush keeps them from then on.
+			for flush := 0; flush < 2; flush++ {
+				change(tree, 100)
+				require.NoError(t, tree.Flush())
+			}
+			change(tree, 50)
+			require.Equal(t, []uint64{first, first + 1, first + 2}, segments())
+			crash(t, tree)
+
+			// The checkpoint and the log after it come to the same items as the file and its log.
+			recovered := NewBpTree(5, opts...)
+			read, err := recovered.RecoverCheckpoint(ck, path, 0.7)
+			require.NoError(t, err)
+			require.Equal(t, manifest, read)
+			require.NoError(t, recovered.Validate())
+			tree, err = Open(path, 4, append(opts, WithWriteAheadLog(SyncAlways, 0))...)
+			require.NoError(t, err)
+			require.NotEmpty(t, collectItems(tree))
+			require.Equal(t, collectItems(tree), collectItems(recovered))
+			require.NoError(t, tree.Close())
+		}
+	}
+
+	// A checkpoint of another tree file is refused.
+	dir := t.TempDir()
+	tree, err := Open(filepath.Join(dir, "tree.bp"), 4, WithWriteAheadLog(SyncNever, 0))
+	require.NoError(t, err)
+	_, err = tree.Checkpoint(filepath.Join(dir, "tree.ck"))
+	require.NoError(t, err)
+	require.NoError(t, tree.Close())
+	_, err = NewBpTree(4).RecoverCheckpoint(filepath.Join(dir, "tree.ck"), filepath.Join(dir, "other.bp"), 1)
+	require.ErrorIs(t, err, ErrCorruptCheckpoint)
+}
+
+// Test_BpTree_Checkpoint_Corrupted 🧫 breaks the dump and the manifest, LoadCheckpoint refuses them
+// and leaves the tree as it was.
+func Test_BpTree_Checkpoint_Corrupted(t *testing.T) {
+	tree := NewBpTree(4)
+	for key := int64(0); key < 500; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: "row"}))
+	}
+	write := func(t *testing.T) (path, dump string) {
+		path = filepath.Join(t.TempDir(), "tree.ck")
+		manifest, err := tree.Checkpoint(path)
+		require.NoError(t, err)
+		dump = filepath.Join(filepath.Dir(path), manifest.Dump)
+		return
+	}
+	check := func(t *testing.T, path string) {
+		loaded := NewBpTree(4)
+		require.NoError(t, loaded.Insert(BpItem{Key: -1}))
+		_, err := loaded.LoadCheckpoint(path, 1)
+		require.ErrorIs(t, err, ErrCorruptCheckpoint)
+		require.Equal(t, []BpItem{{Key: -1}}, collectItems(loaded))
+	}
+
+	t.Run("flipped byte", func(t *testing.T) {
+		path, dump := write(t)
+		raw, err := os.ReadFile(dump)
+		require.NoError(t, err)
+		raw[len(raw)/2] ^= 0xFF
+		require.NoError(t, os.WriteFile(dump, raw, filePermission))
+		check(t, path)
+	})
+
+	t.Run("cut dump", func(t *testing.T) {
+		path, dump := write(t)
+		require.NoError(t, os.Truncate(dump, 100))
+		check(t, path)
+	})
+
+	t.Run("broken manifest", func(t *testing.T) {
+		path, _ := write(t)
+		require.NoError(t, os.WriteFile(path+manifestSuffix, []byte("{"), filePermission))
+		check(t, path)
+	})
+
+	t.Run("dump outside the directory", func(t *testing.T) {
+		path, _ := write(t)
+		for _, name := range []string{"../tree.ck", "sub/tree.ck", "..", ""} {
+			require.NoError(t, writeManifest(path+manifestSuffix, CheckpointManifest{Dump: name}))
+			check(t, path)
+		}
+	})
+
+	t.Run("no manifest", func(t *testing.T) {
+		path, _ := write(t)
+		require.NoError(t, os.Remove(path+manifestSuffix))
+		_, err := NewBpTree(4).LoadCheckpoint(path, 1)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	// The values of a tree of int have no codec.
+	_, err := NewBpTreeG[string, int](4).Checkpoint(filepath.Join(t.TempDir(), "tree.ck"))
+	require.ErrorIs(t, err, ErrValueType)
+}
+
+// Benchmark_BpTree_Checkpoint compares the throughput of Checkpoint with the plain writes in checkpointWriters,
+// writing the same number of bytes and syncing them the same way.
+func Benchmark_BpTree_Checkpoint(b *testing.B) {
+	tree := NewBpTree(64)
+	items := make([]BpItem, 200000)
+	for i := range items {
+		items[i] = BpItem{Key: int64(i), Val: "value " + strconv.Itoa(i)}
+	}
+	require.NoError(b, tree.BulkLoad(items, 1))
+	path := filepath.Join(b.TempDir(), "tree.ck")
+	manifest, err := tree.Checkpoint(path)
+	require.NoError(b, err)
+
+	b.Run("Checkpoint", func(b *testing.B) {
+		b.SetBytes(manifest.Size)
+		for i := 0; i < b.N; i++ {
+			if _, err := tree.Checkpoint(path); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	for _, writer := range checkpointWriters {
+		b.Run(writer.name, func(b *testing.B) {
+			b.SetBytes(manifest.Size)
+			for i := 0; i < b.N; i++ {
+				if err := writer.write(path+".plain", manifest.Size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// checkpointWriters are the plain writes Benchmark_BpTree_Checkpoint compares with.
+var checkpointWriters = []checkpointWriter{{"BufferedWrite", bufferedWrite}}
+
+// checkpointWriter is a plain write of size bytes to the file at path, synced afterward.
+type checkpointWriter struct {
+	name  string
+	write func(path string, size int64) error
+}
+
+// bufferedWrite writes through a buffered writer of the same size as the one of Checkpoint.
+func bufferedWrite(path string, size int64) (err error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermission)
+	if err != nil {
+		return
+	}
+	writer, chunk := bufio.NewWriterSize(file, checkpointBufferSize), make([]byte, 4<<10)
+	for sent := int64(0); sent < size; sent += int64(len(chunk)) {
+		_, _ = writer.Write(chunk[:min(int64(len(chunk)), size-sent)])
+	}
+	if err = writer.Flush(); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
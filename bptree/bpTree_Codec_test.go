@@ -0,0 +1,175 @@
+package bpTree
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// record is a value type without a built-in codec.
+type record struct {
+	Name  string
+	Score float64
+	Tags  []string
+}
+
+// Test_BpTree_Codec 🧫 round-trips values through every built-in codec, and checks the size estimates.
+func Test_BpTree_Codec(t *testing.T) {
+	// roundTrip marshals the value after a prefix, checks the prefix and the size, and unmarshals it.
+	roundTrip := func(t *testing.T, marshal func([]byte) ([]byte, error), size int, unmarshal func([]byte) error) {
+		raw, err := marshal([]byte("prefix"))
+		require.NoError(t, err)
+		require.Equal(t, "prefix", string(raw[:6]))
+		require.Equal(t, size, len(raw)-6)
+		// The value must not refer to the bytes it came from.
+		body := raw[6:]
+		require.NoError(t, unmarshal(body))
+		clear(body)
+	}
+
+	t.Run("bytes and string", func(t *testing.T) {
+		var bytesVal []byte
+		roundTrip(t, func(dst []byte) ([]byte, error) { return BytesCodec{}.Marshal(dst, []byte{1, 2, 3}) },
+			BytesCodec{}.Size([]byte{1, 2, 3}), func(src []byte) (err error) { bytesVal, err = BytesCodec{}.Unmarshal(src); return })
+		require.Equal(t, []byte{1, 2, 3}, bytesVal)
+
+		var stringVal string
+		roundTrip(t, func(dst []byte) ([]byte, error) { return StringCodec{}.Marshal(dst, "row") },
+			StringCodec{}.Size("row"), func(src []byte) (err error) { stringVal, err = StringCodec{}.Unmarshal(src); return })
+		require.Equal(t, "row", stringVal)
+	})
+
+	t.Run("int64", func(t *testing.T) {
+		num := int64(-12345)
+		for _, order := range []binary.ByteOrder{nil, binary.LittleEndian, binary.BigEndian} {
+			codec := Int64Codec{Order: order}
+			raw, err := codec.Marshal(nil, num)
+			require.NoError(t, err)
+			require.Equal(t, codec.order().(binary.AppendByteOrder).AppendUint64(nil, uint64(num)), raw)
+			val, err := codec.Unmarshal(raw)
+			require.NoError(t, err)
+			require.Equal(t, num, val)
+			require.Equal(t, 8, codec.Size(0))
+		}
+		_, err := Int64Codec{}.Unmarshal([]byte{1, 2, 3})
+		require.ErrorIs(t, err, ErrCorruptPage)
+	})
+
+	t.Run("any", func(t *testing.T) {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			codec := AnyCodec{Order: order}
+			for _, val := range []any{nil, int64(-7), 42, 3.5, true, "row", []byte{1, 2, 3}} {
+				var got any
+				roundTrip(t, func(dst []byte) ([]byte, error) { return codec.Marshal(dst, val) },
+					codec.Size(val), func(src []byte) (err error) { got, err = codec.Unmarshal(src); return })
+				require.Equal(t, val, got)
+			}
+			_, err := codec.Marshal(nil, struct{}{})
+			require.ErrorIs(t, err, ErrValueType)
+		}
+	})
+
+	t.Run("json and gob", func(t *testing.T) {
+		val := record{Name: "row", Score: 0.5, Tags: []string{"a", "b"}}
+		for _, codec := range []Codec[record]{JSONCodec[record]{}, GobCodec[record]{}} {
+			var got record
+			roundTrip(t, func(dst []byte) ([]byte, error) { return codec.Marshal(dst, val) },
+				codec.Size(val), func(src []byte) (err error) { got, err = codec.Unmarshal(src); return })
+			require.Equal(t, val, got)
+			_, err := codec.Unmarshal([]byte("{broken"))
+			require.ErrorIs(t, err, ErrCorruptPage)
+		}
+		_, err := JSONCodec[any]{}.Marshal(nil, make(chan int))
+		require.ErrorIs(t, err, ErrValueType)
+		_, err = GobCodec[any]{}.Marshal(nil, make(chan int))
+		require.ErrorIs(t, err, ErrValueType)
+	})
+}
+
+// Test_BpTree_OpenG 🧫 keeps trees of other key and value types in files, with the built-in codecs
+// and with the ones given by the options, through the pages, the write-ahead log and a checkpoint.
+func Test_BpTree_OpenG(t *testing.T) {
+	t.Run("string keys and bytes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tree.bp")
+		tree, err := OpenG[string, []byte](path, 4, WithByteOrder(binary.BigEndian))
+		require.NoError(t, err)
+		for i := 0; i < 300; i++ {
+			require.NoError(t, tree.Insert(BpItemG[string, []byte]{Key: "key" + strconv.Itoa(i), Val: []byte(strconv.Itoa(i))}))
+		}
+		require.NoError(t, tree.Close())
+
+		tree, err = OpenG[string, []byte](path, 4)
+		require.NoError(t, err)
+		require.NoError(t, tree.Validate())
+		require.Equal(t, 300, tree.Len())
+		item, found := tree.Get("key42")
+		require.True(t, found)
+		require.Equal(t, []byte("42"), item.Val)
+		require.NoError(t, tree.Close())
+	})
+
+	for name, codec := range map[string]Codec[record]{"json": JSONCodec[record]{}, "gob": GobCodec[record]{}} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tree.bp")
+			opts := []BpOption{WithValueCodec(codec), WithWriteAheadLog(SyncAlways, time.Second)}
+			tree, err := OpenG[int64, record](path, 4, append(opts, WithPageSize(4096))...)
+			require.NoError(t, err)
+			for key := int64(0); key < 200; key++ {
+				require.NoError(t, tree.Insert(BpItemG[int64, record]{Key: key, Val: record{Name: strconv.Itoa(int(key)), Score: float64(key) / 2}}))
+				if key == 100 {
+					require.NoError(t, tree.Flush())
+				}
+			}
+
+			// The records after the Flush come back from the log.
+			require.NoError(t, tree.file.log.close())
+			require.NoError(t, tree.file.file.Close())
+			tree, err = OpenG[int64, record](path, 4, opts...)
+			require.NoError(t, err)
+			require.NoError(t, tree.Validate())
+			require.Equal(t, 200, tree.Len())
+			item, found := tree.Get(150)
+			require.True(t, found)
+			require.Equal(t, record{Name: "150", Score: 75}, item.Val)
+
+			// A checkpoint loads into a tree in memory with the same codec.
+			_, err = tree.Checkpoint(filepath.Join(dir, "tree.ck"))
+			require.NoError(t, err)
+			require.NoError(t, tree.Close())
+			loaded := NewBpTreeG[int64, record](5, WithValueCodec(codec))
+			_, err = loaded.LoadCheckpoint(filepath.Join(dir, "tree.ck"), 1)
+			require.NoError(t, err)
+			require.Equal(t, 200, loaded.Len())
+			item, found = loaded.Get(199)
+			require.True(t, found)
+			require.Equal(t, record{Name: "199", Score: 99.5}, item.Val)
+		})
+	}
+
+	t.Run("no codec", func(t *testing.T) {
+		// A type without a codec is refused before the file is created.
+		path := filepath.Join(t.TempDir(), "tree.bp")
+		_, err := OpenG[string, record](path, 4)
+		require.ErrorIs(t, err, ErrValueType)
+		_, err = os.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		// A codec of another type is refused the same way, and a tree in memory reports it when it is stored.
+		_, err = OpenG[string, int](path, 4, WithValueCodec[string](StringCodec{}))
+		require.ErrorIs(t, err, ErrValueType)
+		_, err = OpenG[string, int](path, 4, WithKeyCodec[int64](Int64Codec{}))
+		require.ErrorIs(t, err, ErrValueType)
+		_, err = os.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		tree := NewBpTreeG[string, int](4, WithValueCodec[string](StringCodec{}))
+		require.NoError(t, tree.Insert(BpItemG[string, int]{Key: "a", Val: 1}))
+		_, err = tree.Checkpoint(filepath.Join(t.TempDir(), "tree.ck"))
+		require.ErrorIs(t, err, ErrValueType)
+	})
+}
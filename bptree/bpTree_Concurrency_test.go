@@ -0,0 +1,150 @@
+package bpTree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Concurrency 🧫 runs lookups and scans in parallel with insertions and deletions.
+// The writers only touch the keys above the base keys, so the readers always find every base key.
+func Test_BpTree_Concurrency(t *testing.T) {
+	const base, readers, writers, rounds = 2000, 8, 2, 300
+
+	tree := NewBpTree(5, WithUniqueKeys())
+	for key := int64(0); key < base; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+	}
+
+	var wg sync.WaitGroup
+	var failures atomic.Int64
+
+	// The writers insert and remove the keys above the base keys.
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < rounds; i++ {
+				key := base + rng.Int63n(base)
+				if err := tree.Insert(BpItem{Key: key}); err != nil && !errors.Is(err, ErrDuplicateKey) {
+					failures.Add(1)
+				}
+				tree.RemoveValue(BpItem{Key: base + rng.Int63n(base)})
+			}
+		}(w)
+	}
+
+	// The readers look up and scan the base keys at the same time.
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(100 + r)))
+			for i := 0; i < rounds; i++ {
+				key := rng.Int63n(base)
+				if item, found := tree.Get(key); !found || item.Val != key {
+					failures.Add(1)
+				}
+				if items := tree.Range(key, min(key+10, base)); len(items) != int(min(10, base-key)) {
+					failures.Add(1)
+				}
+				count := int64(0)
+				for range tree.Ascend(0, base) {
+					count++
+				}
+				if count != base {
+					failures.Add(1)
+				}
+			}
+		}(r)
+	}
+
+	wg.Wait()
+	require.Zero(t, failures.Load())
+	require.NoError(t, tree.Validate())
+}
+
+// benchStore is what the benchmarks need from a tree.
+type benchStore interface {
+	get(key int64)
+	insert(key int64)
+	remove(key int64)
+}
+
+// rwStore uses the tree directly, lookups share the read lock.
+type rwStore struct {
+	tree *BpTree
+}
+
+func (store *rwStore) get(key int64)    { store.tree.Get(key) }
+func (store *rwStore) insert(key int64) { _ = store.tree.Insert(BpItem{Key: key}) }
+func (store *rwStore) remove(key int64) { store.tree.RemoveValue(BpItem{Key: key}) }
+
+// mutexStore puts every call behind one mutex, the same as the tree did before it had a read lock.
+type mutexStore struct {
+	mutex sync.Mutex
+	tree  *BpTree
+}
+
+func (store *mutexStore) get(key int64) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.tree.Get(key)
+}
+
+func (store *mutexStore) insert(key int64) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	_ = store.tree.Insert(BpItem{Key: key})
+}
+
+func (store *mutexStore) remove(key int64) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.tree.RemoveValue(BpItem{Key: key})
+}
+
+// Benchmark_BpTree_ReadWrite compares the read lock with a single mutex under several read ratios.
+// Run it with -cpu to change the number of parallel goroutines, for example -cpu 1,4,16.
+func Benchmark_BpTree_ReadWrite(b *testing.B) {
+	const keys = 100000
+
+	for _, reads := range []int{50, 90, 99, 100} {
+		for _, name := range []string{"RWMutex", "Mutex"} {
+			b.Run(fmt.Sprintf("reads=%d%%/%s", reads, name), func(b *testing.B) {
+				// Fill the tree with every other key, the writers insert and remove the rest.
+				tree := NewBpTree(32, WithUniqueKeys())
+				for key := int64(0); key < keys; key += 2 {
+					_ = tree.Insert(BpItem{Key: key})
+				}
+				var store benchStore = &rwStore{tree: tree}
+				if name == "Mutex" {
+					store = &mutexStore{tree: tree}
+				}
+
+				var seed atomic.Int64
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					rng := rand.New(rand.NewSource(seed.Add(1)))
+					for pb.Next() {
+						key := rng.Int63n(keys)
+						switch op := rng.Intn(100); {
+						case op < reads:
+							store.get(key)
+						case op%2 == 0:
+							store.insert(key)
+						default:
+							store.remove(key)
+						}
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package bpTree
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_DeleteRange 🧫 removes random ranges from random trees and compares the keys left with a sorted slice.
+func Test_BpTree_DeleteRange(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 16} {
+		for _, opt := range []BpOption{WithUniqueKeys(), WithDuplicates()} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width)))
+			for round := 0; round < 30; round++ {
+				tree := NewBpTree(width, opt)
+				var live []int64
+				for i := 0; i < 1+rng.Intn(600); i++ {
+					key := rng.Int63n(1000)
+					if tree.Insert(BpItem{Key: key}) == nil {
+						live = append(live, key)
+					}
+				}
+				slices.Sort(live)
+
+				// Cut ranges until the tree is empty, some of them wide and some of them narrow.
+				for len(live) > 0 {
+					from := rng.Int63n(1100) - 50
+					to := from + rng.Int63n(1+rng.Int63n(500))
+					kept := slices.DeleteFunc(slices.Clone(live), func(key int64) bool {
+						return key >= from && key < to
+					})
+
+					deleted, err := tree.DeleteRange(from, to)
+					require.NoError(t, err)
+					require.Equal(t, len(live)-len(kept), deleted, "width %d, range [%d, %d)", width, from, to)
+					require.NoError(t, tree.Validate(), "width %d, range [%d, %d)", width, from, to)
+					require.Equal(t, kept, append([]int64{}, snapshotKeys(tree)...))
+					require.Equal(t, len(kept), tree.Len())
+					live = kept
+
+					// The tree keeps working after the cut.
+					if rng.Intn(4) == 0 {
+						key := rng.Int63n(1000)
+						if tree.Insert(BpItem{Key: key}) == nil {
+							live = append(live, key)
+							slices.Sort(live)
+						}
+						require.NoError(t, tree.Validate())
+					}
+				}
+			}
+		}
+	}
+}
+
+// Test_BpTree_DeleteRange_Snapshot 🧫 checks that a range deletion does not change a snapshot taken before it.
+func Test_BpTree_DeleteRange_Snapshot(t *testing.T) {
+	for _, width := range []int{3, 5, 8} {
+		tree := NewBpTree(width, WithCopyOnWrite())
+		var keys []int64
+		for key := int64(0); key < 500; key++ {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+			keys = append(keys, key)
+		}
+
+		rng := rand.New(rand.NewSource(int64(width)))
+		for round := 0; round < 20; round++ {
+			snap, err := tree.Snapshot()
+			require.NoError(t, err)
+			before := snapshotKeys(tree)
+
+			from := rng.Int63n(500)
+			_, err = tree.DeleteRange(from, from+rng.Int63n(100))
+			require.NoError(t, err)
+			require.NoError(t, tree.Validate())
+
+			var seen []int64
+			for key := range snap.All() {
+				seen = append(seen, key)
+			}
+			require.Equal(t, before, seen)
+		}
+	}
+}
+
+// Test_BpTree_PopMin_PopMax 🧫 pops from both ends in random order and compares with a sorted slice.
+func Test_BpTree_PopMin_PopMax(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 8} {
+		for _, opt := range []BpOption{WithDuplicates(), WithCopyOnWrite()} {
+			rng := rand.New(rand.NewSource(int64(width)))
+			tree := NewBpTree(width, opt)
+			var live []int64
+			for i := 0; i < 500; i++ {
+				key := rng.Int63n(200)
+				require.NoError(t, tree.Insert(BpItem{Key: key, Val: i}))
+				live = append(live, key)
+			}
+			slices.Sort(live)
+
+			for len(live) > 0 {
+				// Take a snapshot from time to time, so that copy-on-write clones the ends.
+				if rng.Intn(10) == 0 {
+					if _, err := tree.Snapshot(); err != nil {
+						require.ErrorIs(t, err, ErrCopyOnWriteOff)
+					}
+				}
+
+				var item BpItem
+				var found bool
+				var err error
+				if rng.Intn(2) == 0 {
+					item, found, err = tree.PopMin()
+					require.Equal(t, live[0], item.Key)
+					live = live[1:]
+				} else {
+					item, found, err = tree.PopMax()
+					require.Equal(t, live[len(live)-1], item.Key)
+					live = live[:len(live)-1]
+				}
+				require.True(t, found)
+				require.NoError(t, err)
+				require.NoError(t, tree.Validate())
+				require.Equal(t, len(live), tree.Len())
+			}
+
+			_, found, err := tree.PopMin()
+			require.False(t, found)
+			require.NoError(t, err)
+			_, found, err = tree.PopMax()
+			require.False(t, found)
+			require.NoError(t, err)
+		}
+	}
+
+	// With duplicates, PopMin takes the oldest item of the key and PopMax the newest one.
+	tree := NewBpTree(3)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, tree.Insert(BpItem{Key: 1, Val: i}))
+		require.NoError(t, tree.Insert(BpItem{Key: 2, Val: i}))
+	}
+	var low, high []int
+	for i := 0; i < 10; i++ {
+		item, _, _ := tree.PopMin()
+		low = append(low, item.Val.(int))
+		item, _, _ = tree.PopMax()
+		high = append(high, item.Val.(int))
+	}
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, low)
+	require.Equal(t, []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, high)
+}
+
+// Test_BpTree_DeleteRange_Model 🧫 mixes range deletions with insertions, single deletions and pops at small widths,
+// and checks every step against a sorted slice and Validate, in every mode the deletions take a different way.
+// The snapshots taken on the way must keep their items while the tree changes.
+func Test_BpTree_DeleteRange_Model(t *testing.T) {
+	modes := map[string][]BpOption{
+		"unique":      {WithUniqueKeys()},
+		"duplicates":  {WithDuplicates()},
+		"lazy unique": {WithUniqueKeys(), WithLazyDeletion()},
+		"lazy":        {WithDuplicates(), WithLazyDeletion()},
+		"snapshot":    {WithDuplicates(), WithCopyOnWrite()},
+	}
+	for name, opts := range modes {
+		for _, width := range []int{3, 4, 5} {
+			for seed := int64(0); seed < 9; seed++ {
+				// Use a fixed seed so that the result can be reproduced.
+				rng := rand.New(rand.NewSource(seed))
+				tree := NewBpTree(width, opts...)
+				var model []BpItem // The items in tree order, the duplicates of a key in insertion order.
+				var snaps []*BpSnapshot
+				var expected [][]BpItem
+
+				for step := 0; step < 400; step++ {
+					message := fmt.Sprintf("%s, width %d, seed %d, step %d", name, width, seed, step)
+					key := rng.Int63n(60)
+					switch op := rng.Intn(10); {
+					case op < 5:
+						// A new duplicate goes after the existing ones.
+						at := upperBound(model, key)
+						err := tree.Insert(BpItem{Key: key, Val: step})
+						if tree.cfg.unique && at > 0 && model[at-1].Key == key {
+							require.Error(t, err, message)
+							break
+						}
+						require.NoError(t, err, message)
+						model = slices.Insert(model, at, BpItem{Key: key, Val: step})
+					case op < 6:
+						// RemoveValue removes the newest duplicate.
+						deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+						require.NoError(t, err, message)
+						at := upperBound(model, key) - 1
+						require.Equal(t, at >= 0 && model[at].Key == key, deleted, message)
+						if deleted {
+							model = slices.Delete(model, at, at+1)
+						}
+					case op < 8:
+						// A range of random length, empty ones included.
+						to := key + rng.Int63n(20)
+						kept := slices.DeleteFunc(slices.Clone(model), func(item BpItem) bool {
+							return item.Key >= key && item.Key < to
+						})
+						deleted, err := tree.DeleteRange(key, to)
+						require.NoError(t, err, message)
+						require.Equal(t, len(model)-len(kept), deleted, message)
+						model = kept
+					case op < 9:
+						item, found, err := tree.PopMin()
+						require.NoError(t, err, message)
+						require.Equal(t, len(model) > 0, found, message)
+						if found {
+							require.Equal(t, model[0], item, message)
+							model = model[1:]
+						}
+					default:
+						item, found, err := tree.PopMax()
+						require.NoError(t, err, message)
+						require.Equal(t, len(model) > 0, found, message)
+						if found {
+							require.Equal(t, model[len(model)-1], item, message)
+							model = model[:len(model)-1]
+						}
+					}
+
+					// Every step leaves a valid tree with the items of the model.
+					require.NoError(t, tree.Validate(), message)
+					items := []BpItem{}
+					for key, val := range tree.All() {
+						items = append(items, BpItem{Key: key, Val: val})
+					}
+					require.Equal(t, append([]BpItem{}, model...), items, message)
+					require.Equal(t, len(model), tree.Len(), message)
+
+					// Take a snapshot from time to time.
+					if tree.cfg.cow && step%25 == 0 {
+						snap, err := tree.Snapshot()
+						require.NoError(t, err)
+						snaps = append(snaps, snap)
+						expected = append(expected, append([]BpItem{}, model...))
+					}
+				}
+
+				// Every snapshot still holds the items of its own time.
+				for i, snap := range snaps {
+					items := []BpItem{}
+					for key, val := range snap.All() {
+						items = append(items, BpItem{Key: key, Val: val})
+					}
+					require.Equal(t, expected[i], items, "%s, width %d, seed %d, snapshot %d", name, width, seed, i)
+				}
+			}
+		}
+	}
+}
+
+// upperBound returns the position of the first item whose key is greater than the key.
+func upperBound(items []BpItem, key int64) (at int) {
+	at, _ = slices.BinarySearchFunc(items, key, func(item BpItem, key int64) int {
+		if item.Key > key {
+			return 1
+		}
+		return -1
+	})
+	return
+}
+
+// Benchmark_BpTree_DeleteRange 🧫 expires a sliding time window, by single deletions and by range deletions.
+func Benchmark_BpTree_DeleteRange(b *testing.B) {
+	// fill loads 100000 keys in order, like timestamps.
+	fill := func() *BpTree {
+		items := make([]BpItem, 100000)
+		for i := range items {
+			items[i].Key = int64(i)
+		}
+		tree := NewBpTree(32)
+		_ = tree.BulkLoad(items, 1)
+		return tree
+	}
+
+	b.Run("RemoveValue", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tree := fill()
+			b.StartTimer()
+			for key := int64(0); key < 100000; key++ {
+				tree.RemoveValue(BpItem{Key: key})
+			}
+		}
+	})
+
+	b.Run("DeleteRange1000", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			tree := fill()
+			b.StartTimer()
+			for from := int64(0); from < 100000; from += 1000 {
+				tree.DeleteRange(from, from+1000)
+			}
+		}
+	})
+}
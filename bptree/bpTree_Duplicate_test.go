@@ -0,0 +1,192 @@
+package bpTree
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_RemoveDuplicate 🧫 inserts duplicates with increasing values, removes the oldest, the newest
+// or a matching one at random, and compares the tree with the values of every key in insertion order.
+func Test_BpTree_RemoveDuplicate(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 8} {
+		for _, opts := range [][]BpOption{{}, {WithCopyOnWrite()}, {WithLazyDeletion()}} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width)))
+			tree := NewBpTree(width, opts...)
+			values := map[int64][]int{}
+
+			for step := 0; step < 6000; step++ {
+				// Take a snapshot from time to time, so that copy-on-write clones the path.
+				if step%50 == 0 {
+					if _, err := tree.Snapshot(); err != nil {
+						require.ErrorIs(t, err, ErrCopyOnWriteOff)
+					}
+				}
+
+				key := rng.Int63n(40)
+				vals := values[key]
+				var item BpItem
+				var removed bool
+				var err error
+				switch rng.Intn(5) {
+				case 0:
+					item, removed, err = tree.RemoveOldest(key)
+					require.Equal(t, len(vals) > 0, removed)
+					if len(vals) > 0 {
+						require.Equal(t, vals[0], item.Val)
+						values[key] = vals[1:]
+					}
+				case 1:
+					item, removed, err = tree.RemoveNewest(key)
+					require.Equal(t, len(vals) > 0, removed)
+					if len(vals) > 0 {
+						require.Equal(t, vals[len(vals)-1], item.Val)
+						values[key] = vals[:len(vals)-1]
+					}
+				case 2:
+					// Remove the first value with the same remainder.
+					rem := rng.Intn(3)
+					item, removed, err = tree.RemoveMatch(key, func(val any) bool { return val.(int)%3 == rem })
+					if i := slices.IndexFunc(vals, func(val int) bool { return val%3 == rem }); i >= 0 {
+						require.Equal(t, vals[i], item.Val)
+						values[key] = slices.Delete(slices.Clone(vals), i, i+1)
+					} else {
+						require.False(t, removed)
+					}
+				default:
+					require.NoError(t, tree.Insert(BpItem{Key: key, Val: step}))
+					values[key] = append(vals, step)
+					continue
+				}
+				require.NoError(t, err)
+				if removed {
+					require.Equal(t, key, item.Key)
+				}
+
+				if step%20 == 0 {
+					require.NoError(t, tree.Validate(), "width %d, step %d", width, step)
+					require.Equal(t, expectedItems(values), collectItems(tree))
+				}
+			}
+		}
+	}
+}
+
+// Test_BpTree_InsertionOrder 🧫 checks that the duplicates stay in insertion order,
+// whether they are inserted one by one or in a batch.
+func Test_BpTree_InsertionOrder(t *testing.T) {
+	for _, width := range []int{3, 4, 7} {
+		rng := rand.New(rand.NewSource(int64(width)))
+		single, batch := NewBpTree(width), NewBpTree(width)
+		values := map[int64][]int{}
+		for round := 0; round < 20; round++ {
+			var ops []Op
+			for i := 0; i < 50; i++ {
+				key, val := rng.Int63n(10), round*100+i
+				require.NoError(t, single.Insert(BpItem{Key: key, Val: val}))
+				ops = append(ops, Op{Kind: OpInsert, Item: BpItem{Key: key, Val: val}})
+				values[key] = append(values[key], val)
+			}
+			_, _, err := batch.ApplyBatch(ops)
+			require.NoError(t, err)
+		}
+		require.Equal(t, expectedItems(values), collectItems(single))
+		require.Equal(t, expectedItems(values), collectItems(batch))
+	}
+}
+
+// Test_BpTree_SecondaryIndex 🧫 uses the tree as a secondary index, the key is a shared column and the value a row ID,
+// and removes the rows by their IDs with RemoveExact and RemoveIf.
+func Test_BpTree_SecondaryIndex(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 8} {
+		for _, opts := range [][]BpOption{{}, {WithCopyOnWrite()}, {WithLazyDeletion()}} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width)))
+			tree := NewBpTree(width, opts...)
+			values := map[int64][]int{}
+			for row := 0; row < 2000; row++ {
+				key := rng.Int63n(30)
+				require.NoError(t, tree.Insert(BpItem{Key: key, Val: row}))
+				values[key] = append(values[key], row)
+			}
+
+			// Remove random rows by their IDs, some of them under the wrong key.
+			for i := 0; i < 1000; i++ {
+				key, row := rng.Int63n(30), rng.Intn(2000)
+				item, removed, err := tree.RemoveExact(key, row)
+				require.NoError(t, err)
+				if at := slices.Index(values[key], row); at >= 0 {
+					require.True(t, removed)
+					require.Equal(t, BpItem{Key: key, Val: row}, item)
+					values[key] = slices.Delete(values[key], at, at+1)
+				} else {
+					require.False(t, removed)
+				}
+			}
+			require.NoError(t, tree.Validate())
+			require.Equal(t, expectedItems(values), collectItems(tree))
+
+			// Remove the rows with an even ID under half of the keys.
+			for key := int64(0); key < 30; key += 2 {
+				kept := slices.DeleteFunc(values[key], func(row int) bool { return row%2 == 0 })
+				removed, err := tree.RemoveIf(key, func(val any) bool { return val.(int)%2 == 0 })
+				require.NoError(t, err)
+				require.Equal(t, len(values[key])-len(kept), removed)
+				values[key] = kept
+			}
+			require.NoError(t, tree.Validate())
+			require.Equal(t, expectedItems(values), collectItems(tree))
+			removed, err := tree.RemoveIf(0, func(val any) bool { return val.(int)%2 == 0 })
+			require.NoError(t, err)
+			require.Zero(t, removed)
+		}
+	}
+
+	// A value that is not comparable equals nothing, and does not panic.
+	tree := NewBpTree(4)
+	require.NoError(t, tree.Insert(BpItem{Key: 1, Val: []int{1}}))
+	require.NoError(t, tree.Insert(BpItem{Key: 1, Val: nil}))
+	require.NoError(t, tree.Insert(BpItem{Key: 1, Val: "row"}))
+	_, removed, _ := tree.RemoveExact(1, []int{1})
+	require.False(t, removed)
+	item, removed, _ := tree.RemoveExact(1, "row")
+	require.True(t, removed)
+	require.Equal(t, "row", item.Val)
+	_, removed, _ = tree.RemoveExact(1, nil)
+	require.True(t, removed)
+	require.Equal(t, 1, tree.Len())
+}
+
+// expectedItems lists the items of the values by key, and by insertion order within a key.
+func expectedItems(values map[int64][]int) (items []BpItem) {
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		for _, val := range values[key] {
+			items = append(items, BpItem{Key: key, Val: val})
+		}
+	}
+	return
+}
+
+// collectItems lists the unmasked items of the tree in ascending order.
+func collectItems(tree *BpTree) (items []BpItem) {
+	for key, val := range tree.All() {
+		items = append(items, BpItem{Key: key, Val: val})
+	}
+	return
+}
+
+// shuffleSlice randomly shuffles the elements in the slice.
+func shuffleSlice(slice []int64, rng *rand.Rand) {
+	// Iterate through the slice in reverse order, starting from the last element.
+	for i := len(slice) - 1; i > 0; i-- {
+		// Generate a random index 'j' between 0 and i (inclusive).
+		j := rng.Intn(i + 1)
+
+		// Swap the elements at indices i and j.
+		slice[i], slice[j] = slice[j], slice[i]
+	}
+}
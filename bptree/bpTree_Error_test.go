@@ -0,0 +1,88 @@
+package bpTree
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Insert_Corrupted 🧫 checks that Insert reports a corrupted index, releases the lock and changes nothing.
+func Test_BpTree_Insert_Corrupted(t *testing.T) {
+	t.Run("index node", func(t *testing.T) {
+		tree := NewBpTree(4)
+		for key := int64(1); key <= 100; key++ {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+		}
+		require.NotEmpty(t, tree.root.IndexNodes)
+
+		// Give the leftmost bottom index node one key too many.
+		node := tree.root
+		for len(node.IndexNodes) > 0 {
+			node = node.IndexNodes[0]
+		}
+		node.Index = append(node.Index, node.Index[len(node.Index)-1])
+		before := snapshotKeys(tree)
+		version := tree.version
+
+		// The insertion fails with the offending index.
+		err := tree.Insert(BpItem{Key: 0})
+		require.ErrorIs(t, err, ErrIndexCorrupted)
+		var corrupted *IndexCorruptedError[int64]
+		require.True(t, errors.As(err, &corrupted))
+		require.Equal(t, node.Index, corrupted.Index)
+
+		// The lock has been released and the tree is unchanged.
+		require.Equal(t, before, snapshotKeys(tree))
+		require.Equal(t, version, tree.version)
+		require.False(t, tree.Contains(0))
+	})
+
+	t.Run("both kinds of child nodes", func(t *testing.T) {
+		tree := NewBpTree(3)
+		for key := int64(1); key <= 100; key++ {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+		}
+
+		// An index node above the bottom also holds a data node, the leaf below it would have been split.
+		tree.root.IndexNodes[0].DataNodes = []*BpData{{}}
+		before := snapshotKeys(tree)
+		err := tree.Insert(BpItem{Key: 0})
+		require.ErrorIs(t, err, ErrIndexCorrupted)
+		require.Equal(t, before, snapshotKeys(tree))
+		require.False(t, tree.Contains(0))
+	})
+
+	t.Run("root data node", func(t *testing.T) {
+		tree := NewBpTree(4)
+		require.NoError(t, tree.Insert(BpItem{Key: 1}))
+
+		// A root without an index must hold exactly one data node.
+		tree.root.DataNodes = append(tree.root.DataNodes, &BpData{})
+		err := tree.Insert(BpItem{Key: 2})
+		require.ErrorIs(t, err, ErrIndexCorrupted)
+		require.Equal(t, []int64{1}, snapshotKeys(tree))
+
+		// InsertValue keeps panicking with the same error.
+		require.PanicsWithError(t, err.Error(), func() {
+			tree.InsertValue(BpItem{Key: 2})
+		})
+		require.Equal(t, []int64{1}, snapshotKeys(tree))
+	})
+
+	t.Run("index without children", func(t *testing.T) {
+		tree := NewBpTree(4)
+		tree.root = &BpIndex{Index: []int64{5}}
+		err := tree.Insert(BpItem{Key: 1})
+		require.ErrorIs(t, err, ErrIndexCorrupted)
+		require.Contains(t, err.Error(), "[5]")
+	})
+}
+
+// snapshotKeys collects the keys along the data node links.
+func snapshotKeys(tree *BpTree) (keys []int64) {
+	for key := range tree.All() {
+		keys = append(keys, key)
+	}
+	return
+}
@@ -0,0 +1,165 @@
+package bpTree
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_File 🧫 changes a tree opened from a file, flushes and reopens it again and again,
+// and compares the items with the ones kept in memory.
+func Test_BpTree_File(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for _, opts := range [][]BpOption{{WithUniqueKeys()}, {WithDuplicates(), WithLazyDeletion()}} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(1))
+			path := filepath.Join(t.TempDir(), "tree.bp")
+			tree, err := Open(path, 5, append(opts, WithByteOrder(order), WithPageSize(1024))...)
+			require.NoError(t, err)
+
+			// memory receives the same operations, without a file.
+			memory := NewBpTree(5, opts...)
+			values := []any{nil, int64(-7), 42, 3.5, true, "row", []byte{1, 2, 3}}
+			for round := 0; round < 30; round++ {
+				for i := 0; i < 200; i++ {
+					item := BpItem{Key: rng.Int63n(500), Val: values[rng.Intn(len(values))]}
+					if rng.Intn(3) > 0 {
+						require.Equal(t, memory.Insert(item), tree.Insert(item))
+					} else {
+						deleted, _, _, _ := memory.RemoveValue(item)
+						deletedToo, _, _, _ := tree.RemoveValue(item)
+						require.Equal(t, deleted, deletedToo)
+					}
+				}
+				require.NoError(t, tree.Flush())
+
+				// Reopen the file from time to time, the tree comes back as it was.
+				if round%3 == 0 {
+					require.NoError(t, tree.Close())
+					tree, err = Open(path, 3)
+					require.NoError(t, err)
+					require.NoError(t, tree.Validate())
+					require.Equal(t, memory.Len(), tree.Len())
+					require.Equal(t, memory.Masked(), tree.Masked())
+				}
+				require.Equal(t, collectItems(memory), collectItems(tree))
+
+				// The free-list gives the pages of the old nodes back, the file holds at most
+				// the nodes of two versions and a few more.
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				nodes := int64(0)
+				tree.root.eachPage(func(uint32) { nodes++ })
+				require.LessOrEqual(t, info.Size()/1024, 3*nodes)
+			}
+			require.NoError(t, tree.Close())
+		}
+	}
+}
+
+// Test_BpTree_File_Corrupted 🧫 breaks the file in different ways and checks what Open makes of it.
+func Test_BpTree_File_Corrupted(t *testing.T) {
+	// write opens a new file with the keys in it.
+	write := func(t *testing.T, keys int) (path string) {
+		path = filepath.Join(t.TempDir(), "tree.bp")
+		tree, err := Open(path, 4, WithPageSize(1024))
+		require.NoError(t, err)
+		for key := 0; key < keys; key++ {
+			require.NoError(t, tree.Insert(BpItem{Key: int64(key), Val: key}))
+		}
+		require.NoError(t, tree.Close())
+		return
+	}
+	// flip changes one byte of the file.
+	flip := func(t *testing.T, path string, offset int64) {
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		require.NoError(t, err)
+		buf := make([]byte, 1)
+		_, err = file.ReadAt(buf, offset)
+		require.NoError(t, err)
+		_, err = file.WriteAt([]byte{^buf[0]}, offset)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	t.Run("torn header", func(t *testing.T) {
+		// The second flush writes the other header slot, breaking it brings back the first version.
+		path := write(t, 100)
+		tree, err := Open(path, 4)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(BpItem{Key: 1000}))
+		require.NoError(t, tree.Close())
+		header, ok := decodeHeader(readAt(t, path, 0, headerSize))
+		require.True(t, ok)
+		newest := header.seq % 2
+		if other, ok := decodeHeader(readAt(t, path, headerSlotSize, headerSize)); ok && other.seq > header.seq {
+			newest = other.seq % 2
+		}
+		flip(t, path, int64(newest)*headerSlotSize+20)
+
+		tree, err = Open(path, 4)
+		require.NoError(t, err)
+		require.Equal(t, 100, tree.Len())
+		require.NoError(t, tree.Close())
+
+		// Without any intact header the file cannot be opened.
+		flip(t, path, int64(1-newest)*headerSlotSize+20)
+		_, err = Open(path, 4)
+		require.ErrorIs(t, err, ErrCorruptPage)
+	})
+
+	t.Run("broken page", func(t *testing.T) {
+		path := write(t, 100)
+		flip(t, path, 3*1024+100)
+		_, err := Open(path, 4)
+		require.ErrorIs(t, err, ErrCorruptPage)
+	})
+
+	t.Run("cut file", func(t *testing.T) {
+		path := write(t, 100)
+		require.NoError(t, os.Truncate(path, 5*1024))
+		_, err := Open(path, 4)
+		require.ErrorIs(t, err, ErrCorruptPage)
+	})
+}
+
+// Test_BpTree_File_Errors 🧫 checks the errors of Flush and Close.
+func Test_BpTree_File_Errors(t *testing.T) {
+	// A tree in memory has no file.
+	tree := NewBpTree(4)
+	require.ErrorIs(t, tree.Flush(), ErrNotPersistent)
+	require.ErrorIs(t, tree.Close(), ErrNotPersistent)
+
+	// A value that does not fit in a page, or whose type cannot be stored, stops Flush.
+	tree, err := Open(filepath.Join(t.TempDir(), "tree.bp"), 4, WithPageSize(1024))
+	require.NoError(t, err)
+	require.NoError(t, tree.Insert(BpItem{Key: 1, Val: make([]byte, 2000)}))
+	require.ErrorIs(t, tree.Flush(), ErrPageOverflow)
+	_, _, _, err = tree.RemoveValue(BpItem{Key: 1})
+	require.NoError(t, err)
+	require.NoError(t, tree.Insert(BpItem{Key: 1, Val: struct{}{}}))
+	require.ErrorIs(t, tree.Flush(), ErrValueType)
+	_, _, _, err = tree.RemoveValue(BpItem{Key: 1})
+	require.NoError(t, err)
+	require.NoError(t, tree.Close())
+	require.ErrorIs(t, tree.Close(), ErrNotPersistent)
+
+	// Only the two byte orders of encoding/binary are supported.
+	_, err = Open(filepath.Join(t.TempDir(), "tree.bp"), 4, WithByteOrder(binary.NativeEndian))
+	require.Error(t, err)
+}
+
+// readAt reads n bytes of the file at the offset.
+func readAt(t *testing.T, path string, offset int64, n int) []byte {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	buf := make([]byte, n)
+	_, err = file.ReadAt(buf, offset)
+	require.NoError(t, err)
+	return buf
+}
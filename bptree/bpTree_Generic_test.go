@@ -0,0 +1,121 @@
+package bpTree
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTreeG_String 🧫 runs bulk insert and delete with string keys ordered by cmp.Compare.
+func Test_BpTreeG_String(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 8} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(int64(width)))
+
+		// Insert unique random strings.
+		tree := NewBpTreeG[string, int](width)
+		keys := make([]string, 0, 1000)
+		for _, n := range rng.Perm(1000) {
+			key := fmt.Sprintf("key-%04d", n)
+			tree.InsertValue(BpItemG[string, int]{Key: key, Val: n})
+			keys = append(keys, key)
+		}
+
+		// The items come out sorted, and every key can be found.
+		slices.Sort(keys)
+		var got []string
+		for key, val := range tree.All() {
+			require.Equal(t, fmt.Sprintf("key-%04d", val), key)
+			got = append(got, key)
+		}
+		require.Equal(t, keys, got, "width %d", width)
+		require.False(t, tree.Contains("key-1000"))
+
+		// Remove every key in random order; the tree must become empty.
+		shuffleStrings(keys, rng)
+		for _, key := range keys {
+			deleted, _, _, err := tree.RemoveValue(BpItemG[string, int]{Key: key})
+			require.True(t, deleted, "width %d, key %s", width, key)
+			require.NoError(t, err)
+		}
+		for range tree.All() {
+			t.Fatal("the tree should be empty")
+		}
+	}
+}
+
+// Test_BpTreeG_Func 🧫 checks trees whose keys are ordered by a user-supplied compare function.
+func Test_BpTreeG_Func(t *testing.T) {
+	// Byte slices are not comparable, so they need bytes.Compare.
+	byteTree := NewBpTreeFunc[[]byte, string](4, bytes.Compare)
+	for _, word := range []string{"pear", "apple", "fig", "banana", "cherry", "date", "kiwi"} {
+		byteTree.InsertValue(BpItemG[[]byte, string]{Key: []byte(word), Val: word})
+	}
+	var words []string
+	for _, val := range byteTree.All() {
+		words = append(words, val)
+	}
+	require.Equal(t, []string{"apple", "banana", "cherry", "date", "fig", "kiwi", "pear"}, words)
+	item, found := byteTree.Get([]byte("fig"))
+	require.True(t, found)
+	require.Equal(t, "fig", item.Val)
+
+	// Composite keys are ordered field by field.
+	type composite struct {
+		group int
+		name  string
+	}
+	compositeTree := NewBpTreeFunc[composite, any](3, func(a, b composite) int {
+		return cmp.Or(cmp.Compare(a.group, b.group), cmp.Compare(a.name, b.name))
+	})
+	for group := 3; group >= 1; group-- {
+		for _, name := range []string{"c", "a", "b"} {
+			compositeTree.InsertValue(BpItemG[composite, any]{Key: composite{group, name}})
+		}
+	}
+	var keys []composite
+	for key := range compositeTree.Ascend(composite{2, ""}, composite{3, ""}) {
+		keys = append(keys, key)
+	}
+	require.Equal(t, []composite{{2, "a"}, {2, "b"}, {2, "c"}}, keys)
+
+	// A reversed compare function gives a descending tree.
+	descending := NewBpTreeFunc[int64, any](3, func(a, b int64) int { return cmp.Compare(b, a) })
+	for key := int64(1); key <= 50; key++ {
+		descending.InsertValue(BpItemG[int64, any]{Key: key})
+	}
+	want := int64(50)
+	for key := range descending.All() {
+		require.Equal(t, want, key)
+		want--
+	}
+}
+
+// Test_BpTree_ZeroKey 🧫 checks that zero and negative keys are ordinary keys.
+func Test_BpTree_ZeroKey(t *testing.T) {
+	for _, width := range []int{3, 4, 5} {
+		tree := NewBpTree(width)
+		for key := int64(-50); key <= 50; key++ {
+			tree.InsertValue(BpItem{Key: key})
+		}
+		want := int64(-50)
+		for key := range tree.All() {
+			require.Equal(t, want, key)
+			want++
+		}
+		require.Equal(t, int64(51), want)
+		require.True(t, tree.Contains(0))
+	}
+}
+
+// shuffleStrings randomly shuffles the elements in the slice.
+func shuffleStrings(slice []string, rng *rand.Rand) {
+	rng.Shuffle(len(slice), func(i, j int) {
+		slice[i], slice[j] = slice[j], slice[i]
+	})
+}
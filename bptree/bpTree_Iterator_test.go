@@ -0,0 +1,166 @@
+package bpTree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Iterator 🧫 walks the tree in both directions and compares the result with sorted keys.
+func Test_BpTree_Iterator(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 8} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(int64(width)))
+
+		// Insert unique random keys, then remove a third of them.
+		tree := NewBpTree(width)
+		keys := make([]int64, 0, 1000)
+		for _, n := range rng.Perm(1000) {
+			key := int64(n)*2 + 1
+			tree.InsertValue(BpItem{Key: key, Val: key * 10})
+			keys = append(keys, key)
+		}
+		shuffleSlice(keys, rng)
+		for _, key := range keys[:300] {
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+			require.True(t, deleted)
+			require.NoError(t, err)
+		}
+		keys = keys[300:]
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+		// Ascending walk.
+		it := tree.Iterator()
+		var got []int64
+		for ok := it.First(); ok; ok = it.Next() {
+			require.Equal(t, it.Key()*10, it.Value())
+			got = append(got, it.Key())
+		}
+		require.Equal(t, keys, got, "width %d", width)
+		require.False(t, it.Valid())
+
+		// Descending walk.
+		got = got[:0]
+		for ok := it.Last(); ok; ok = it.Prev() {
+			got = append(got, it.Key())
+		}
+		for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+			got[i], got[j] = got[j], got[i]
+		}
+		require.Equal(t, keys, got, "width %d", width)
+
+		// Seek lands on the first key not less than the target.
+		for _, target := range []int64{0, 1, 2, 999, 1000, 1999, 2000} {
+			ix := sort.Search(len(keys), func(i int) bool { return keys[i] >= target })
+			require.Equal(t, ix < len(keys), it.Seek(target), "width %d, target %d", width, target)
+			if ix < len(keys) {
+				require.Equal(t, keys[ix], it.Key())
+			}
+		}
+
+		// Range and ReverseRange share the same half-open interval.
+		items := tree.Range(500, 1500)
+		lower := sort.Search(len(keys), func(i int) bool { return keys[i] >= 500 })
+		upper := sort.Search(len(keys), func(i int) bool { return keys[i] >= 1500 })
+		require.Len(t, items, upper-lower)
+		for i, item := range items {
+			require.Equal(t, keys[lower+i], item.Key)
+		}
+		reversed := tree.ReverseRange(500, 1500)
+		require.Len(t, reversed, upper-lower)
+		for i, item := range reversed {
+			require.Equal(t, keys[upper-1-i], item.Key)
+		}
+		require.Empty(t, tree.Range(1500, 500))
+		require.Len(t, tree.ReverseRange(0, 5000), len(keys))
+	}
+}
+
+// Test_BpTree_Iterator_Modified 🧫 checks that an iterator keeps its position while the tree is modified.
+func Test_BpTree_Iterator_Modified(t *testing.T) {
+	tree := NewBpTree(3)
+	for key := int64(1); key <= 100; key++ {
+		tree.InsertValue(BpItem{Key: key})
+	}
+
+	// Remove every item right after visiting it; the iterator must still reach every key once.
+	it := tree.Iterator()
+	var got []int64
+	for ok := it.First(); ok; ok = it.Next() {
+		got = append(got, it.Key())
+		deleted, _, _, err := tree.RemoveValue(BpItem{Key: it.Key()})
+		require.True(t, deleted)
+		require.NoError(t, err)
+	}
+	require.Len(t, got, 100)
+	for i, key := range got {
+		require.Equal(t, int64(i+1), key)
+	}
+
+	// Items inserted ahead of the iterator are visited; items inserted behind are not.
+	for key := int64(10); key <= 50; key += 10 {
+		tree.InsertValue(BpItem{Key: key})
+	}
+	require.True(t, it.Seek(20))
+	tree.InsertValue(BpItem{Key: 5})
+	tree.InsertValue(BpItem{Key: 25})
+	got = got[:0]
+	for ok := it.Next(); ok; ok = it.Next() {
+		got = append(got, it.Key())
+	}
+	require.Equal(t, []int64{25, 30, 40, 50}, got)
+
+	// Walking back after the current item is removed continues from its predecessor.
+	require.True(t, it.Seek(30))
+	deleted, _, _, err := tree.RemoveValue(BpItem{Key: 30})
+	require.True(t, deleted)
+	require.NoError(t, err)
+	require.True(t, it.Prev())
+	require.Equal(t, int64(25), it.Key())
+
+	// Masked items are skipped.
+	tree = NewBpTree(4)
+	for key := int64(1); key <= 3; key++ {
+		tree.InsertValue(BpItem{Key: key})
+	}
+	tree.root.DataNodes[0].Items[1].Mask = true
+	require.Equal(t, []BpItem{{Key: 1}, {Key: 3}}, tree.Range(0, 10))
+
+	// An empty tree has nothing to walk.
+	it = NewBpTree(3).Iterator()
+	require.False(t, it.First())
+	require.False(t, it.Last())
+	require.False(t, it.Seek(1))
+}
+
+// Test_BpTree_Iterator_Concurrent 🧫 walks the tree while another goroutine keeps inserting, run it with -race.
+func Test_BpTree_Iterator_Concurrent(t *testing.T) {
+	tree := NewBpTree(5)
+	for key := int64(1); key <= 1000; key += 2 {
+		tree.InsertValue(BpItem{Key: key})
+	}
+
+	// Insert the even keys in the background.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for key := int64(2); key <= 1000; key += 2 {
+			tree.InsertValue(BpItem{Key: key})
+		}
+	}()
+
+	// The keys must stay in ascending order and every odd key must be visited.
+	it := tree.Iterator()
+	previous, odd := int64(0), 0
+	for ok := it.First(); ok; ok = it.Next() {
+		require.Greater(t, it.Key(), previous)
+		previous = it.Key()
+		if previous%2 == 1 {
+			odd++
+		}
+	}
+	<-done
+	require.Equal(t, 500, odd)
+}
@@ -0,0 +1,134 @@
+package bpTree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_LazyDeletion 🧫 inserts and removes random keys in lazy deletion mode, compacts from time to time,
+// and compares the tree with a sorted slice.
+func Test_BpTree_LazyDeletion(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 8} {
+		for _, opt := range []BpOption{WithUniqueKeys(), WithDuplicates()} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width)))
+			tree := NewBpTree(width, opt, WithLazyDeletion(), WithCopyOnWrite())
+
+			var live []int64
+			masked := 0
+			for step := 0; step < 4000; step++ {
+				key := rng.Int63n(300)
+				if rng.Intn(2) == 0 {
+					if tree.Insert(BpItem{Key: key}) == nil {
+						live = append(live, key)
+					}
+				} else if deleted, _, _, err := tree.RemoveValue(BpItem{Key: key}); deleted {
+					require.NoError(t, err)
+					live = slices.Delete(live, slices.Index(live, key), slices.Index(live, key)+1)
+				}
+
+				if step%100 == 0 {
+					// The reads skip the masked items.
+					sorted := slices.Sorted(slices.Values(live))
+					require.NoError(t, tree.Validate())
+					require.Equal(t, sorted, snapshotKeys(tree))
+					require.Equal(t, len(sorted), tree.Len())
+					for probe := int64(0); probe < 300; probe += 11 {
+						_, found := tree.Get(probe)
+						require.Equal(t, slices.Contains(sorted, probe), found)
+						require.Equal(t, len(slices.DeleteFunc(slices.Clone(sorted), func(key int64) bool { return key >= probe })), tree.Rank(probe))
+					}
+					if len(sorted) > 0 {
+						item, _ := tree.Select(len(sorted) / 2)
+						require.Equal(t, sorted[len(sorted)/2], item.Key)
+					}
+
+					// A snapshot taken before Compact keeps its view.
+					snap, err := tree.Snapshot()
+					require.NoError(t, err)
+					masked = tree.Masked()
+					if step%500 == 0 {
+						removed, err := tree.Compact()
+						require.NoError(t, err)
+						require.Equal(t, masked, removed)
+						require.Zero(t, tree.Masked())
+						require.NoError(t, tree.Validate())
+						require.Equal(t, sorted, snapshotKeys(tree))
+					}
+					var seen []int64
+					for key := range snap.All() {
+						seen = append(seen, key)
+					}
+					require.Equal(t, sorted, seen)
+				}
+			}
+			require.Positive(t, masked)
+		}
+	}
+}
+
+// Test_BpTree_LazyDeletion_Revive 🧫 checks that a deletion leaves the structure alone
+// and that an insertion of the same key uses the masked item again.
+func Test_BpTree_LazyDeletion_Revive(t *testing.T) {
+	tree := NewBpTree(4, WithUniqueKeys(), WithLazyDeletion())
+	for key := int64(0); key < 100; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+	}
+
+	// The deletions only mask, no structural event happens.
+	tracer := &countTracer{counts: map[TraceKind]int{}, last: map[TraceKind]TraceEvent{}}
+	tree.SetTracer(tracer)
+	for key := int64(0); key < 100; key += 2 {
+		deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+		require.True(t, deleted)
+		require.NoError(t, err)
+	}
+	require.Equal(t, map[TraceKind]int{TraceMask: 50}, tracer.counts)
+	require.Equal(t, 50, tree.Masked())
+	deleted, _, _, _ := tree.RemoveValue(BpItem{Key: 0})
+	require.False(t, deleted)
+
+	// The insertion takes the place of the masked item, so the key is unique again.
+	require.NoError(t, tree.Insert(BpItem{Key: 10, Val: "new"}))
+	require.ErrorIs(t, tree.Insert(BpItem{Key: 10}), ErrDuplicateKey)
+	require.Equal(t, 49, tree.Masked())
+	item, found := tree.Get(10)
+	require.True(t, found)
+	require.Equal(t, "new", item.Val)
+
+	// Compact removes the rest.
+	removed, err := tree.Compact()
+	require.NoError(t, err)
+	require.Equal(t, 49, removed)
+	require.Equal(t, 51, tree.Len())
+	require.NoError(t, tree.Validate())
+}
+
+// Benchmark_BpTree_LazyDeletion 🧫 measures the deletions alone, with and without lazy deletion.
+func Benchmark_BpTree_LazyDeletion(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []BpOption
+	}{
+		{"Remove", nil},
+		{"Mask", []BpOption{WithLazyDeletion()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			// Load the keys outside of the timer, then remove them in random order.
+			items := make([]BpItem, b.N)
+			for i := range items {
+				items[i].Key = int64(i)
+			}
+			tree := NewBpTree(32, bench.opts...)
+			_ = tree.BulkLoad(items, 0.75)
+			keys := rand.New(rand.NewSource(1)).Perm(b.N)
+			b.ResetTimer()
+			for _, key := range keys {
+				tree.RemoveValue(BpItem{Key: int64(key)})
+			}
+		})
+	}
+}
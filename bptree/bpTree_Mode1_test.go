@@ -1,3 +1,5 @@
+//go:build linux
+
 package bpTree
 
 import (
@@ -147,6 +149,9 @@ Loop:
 	err := progressBar.Report(len(testMode1Name + "; Width: XX"))
 	assert.NoError(t, err)
 
+	// The structure must still be sound after the whole run.
+	require.NoError(t, root.Validate())
+
 	// Print the B Plus tree structure.
 	root.root.Print()
 }
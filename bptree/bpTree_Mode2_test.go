@@ -1,3 +1,5 @@
+//go:build linux
+
 package bpTree
 
 import (
@@ -146,6 +148,9 @@ Loop:
 	err := progressBar.Report(len(testMode2Name + "; Width: XX"))
 	assert.NoError(t, err)
 
+	// The structure must still be sound after the whole run.
+	require.NoError(t, root.Validate())
+
 	// Print the B Plus tree structure.
 	root.root.Print()
 }
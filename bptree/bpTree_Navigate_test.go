@@ -0,0 +1,89 @@
+package bpTree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Navigate 🧫 masks random items and checks Min, Max, Floor, Ceiling, Lower and Higher
+// against the sorted unmasked keys.
+func Test_BpTree_Navigate(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 8} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(int64(width)))
+		tree := NewBpTree(width)
+		for i := 0; i < 400; i++ {
+			key := rng.Int63n(200) * 2
+			require.NoError(t, tree.Insert(BpItem{Key: key, Val: i}))
+		}
+
+		// Mask about a third of the items, so that the queries have to cross data nodes to skip them.
+		var live []BpItem
+		for data := tree.root.BpDataHead(); data != nil; data = data.Next {
+			for i := range data.Items {
+				if data.Items[i].Mask = rng.Intn(3) == 0; !data.Items[i].Mask {
+					live = append(live, data.Items[i])
+				}
+			}
+		}
+
+		// check compares the result with the expected position in live, -1 means not found.
+		check := func(name string, key int64, expected int, item BpItem, found bool) {
+			if expected < 0 {
+				require.False(t, found, "%s(%d), width %d", name, key, width)
+				return
+			}
+			require.True(t, found, "%s(%d), width %d", name, key, width)
+			require.Equal(t, live[expected], item, "%s(%d), width %d", name, key, width)
+		}
+
+		item, found := tree.Min()
+		check("Min", 0, 0, item, found)
+		item, found = tree.Max()
+		check("Max", 0, len(live)-1, item, found)
+
+		for key := int64(-2); key <= 402; key++ {
+			// The first live item not less than and greater than the key.
+			ceiling, higher := len(live), len(live)
+			for i := len(live) - 1; i >= 0; i-- {
+				if live[i].Key >= key {
+					ceiling = i
+				}
+				if live[i].Key > key {
+					higher = i
+				}
+			}
+
+			// Ceiling and Higher take the first item of a key, Floor and Lower the last one.
+			item, found = tree.Ceiling(key)
+			check("Ceiling", key, orNone(ceiling, len(live)), item, found)
+			item, found = tree.Higher(key)
+			check("Higher", key, orNone(higher, len(live)), item, found)
+			item, found = tree.Floor(key)
+			check("Floor", key, higher-1, item, found)
+			item, found = tree.Lower(key)
+			check("Lower", key, ceiling-1, item, found)
+		}
+	}
+
+	// An empty tree has nothing to find.
+	tree := NewBpTree(4)
+	_, found := tree.Min()
+	require.False(t, found)
+	_, found = tree.Max()
+	require.False(t, found)
+	_, found = tree.Floor(1)
+	require.False(t, found)
+	_, found = tree.Ceiling(1)
+	require.False(t, found)
+}
+
+// orNone turns the position n, one past the end, into -1.
+func orNone(i, n int) int {
+	if i == n {
+		return -1
+	}
+	return i
+}
@@ -0,0 +1,219 @@
+package bpTree
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Pool 🧫 keeps a tree many times larger than its buffer pool in a file,
+// and checks that the pool stays within its capacity and that the evicted data nodes come back from the file.
+func Test_BpTree_Pool(t *testing.T) {
+	// Use a fixed seed so that the result can be reproduced.
+	rng := rand.New(rand.NewSource(1))
+	path := filepath.Join(t.TempDir(), "tree.bp")
+	tree, err := Open(path, 4, WithPageSize(1024), WithLazyDeletion(), WithBufferPool(minPoolPages))
+	require.NoError(t, err)
+	memory := NewBpTree(4, WithLazyDeletion())
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 300; i++ {
+			item := BpItem{Key: rng.Int63n(2000), Val: i}
+			if rng.Intn(4) > 0 {
+				require.NoError(t, memory.Insert(item))
+				require.NoError(t, tree.Insert(item))
+			} else {
+				memory.RemoveValue(item)
+				tree.RemoveValue(item)
+			}
+		}
+		require.NoError(t, tree.Flush())
+
+		// The evicted data nodes are faulted in again, and the counts do not need them.
+		require.NoError(t, tree.Validate())
+		require.Equal(t, collectItems(memory), collectItems(tree))
+		require.Equal(t, memory.Len(), tree.Len())
+		require.Equal(t, memory.Masked(), tree.Masked())
+		stats, err := tree.PoolStats()
+		require.NoError(t, err)
+		require.LessOrEqual(t, stats.Resident, stats.Capacity)
+	}
+
+	// Flush handed the written data nodes to the pool, and most of them were evicted.
+	stats, err := tree.PoolStats()
+	require.NoError(t, err)
+	require.Equal(t, minPoolPages, stats.Capacity)
+	require.Positive(t, stats.WriteBacks)
+	require.Positive(t, stats.Evictions)
+	require.Positive(t, stats.Misses)
+	require.NoError(t, tree.Close())
+
+	// Open reads every data node once and keeps the capacity of them.
+	tree, err = Open(path, 4, WithBufferPool(minPoolPages))
+	require.NoError(t, err)
+	nodes := 0
+	tree.root.eachData(func(*BpDataG[int64, any]) { nodes++ })
+	require.Greater(t, nodes, 4*minPoolPages)
+	stats, err = tree.PoolStats()
+	require.NoError(t, err)
+	require.Equal(t, uint64(nodes), stats.Misses)
+	require.Equal(t, uint64(nodes-minPoolPages), stats.Evictions)
+	require.Equal(t, minPoolPages, stats.Resident)
+
+	// Reading an evicted data node misses, reading it again right away hits.
+	var key int64
+	tree.root.eachData(func(data *BpDataG[int64, any]) {
+		if data.frame.evicted.Load() && data.Previous != nil {
+			key = data.scan()[0].Key
+		}
+	})
+	before, err := tree.PoolStats()
+	require.NoError(t, err)
+	_, found := tree.Get(key)
+	require.True(t, found)
+	missed, err := tree.PoolStats()
+	require.NoError(t, err)
+	require.Greater(t, missed.Misses, before.Misses)
+	_, found = tree.Get(key)
+	require.True(t, found)
+	hit, err := tree.PoolStats()
+	require.NoError(t, err)
+	require.Equal(t, missed.Misses, hit.Misses)
+	require.Greater(t, hit.Hits, missed.Hits)
+	require.LessOrEqual(t, hit.Resident, hit.Capacity)
+
+	// Close faults every data node in, the tree keeps working in memory.
+	require.NoError(t, tree.Close())
+	require.Equal(t, collectItems(memory), collectItems(tree))
+
+	// A tree in memory has no pool.
+	_, err = NewBpTree(4).PoolStats()
+	require.ErrorIs(t, err, ErrNotPersistent)
+}
+
+// Test_BpTree_Pool_Pinned 🧫 checks that the CLOCK hand passes over a pinned data node,
+// and that the node can be evicted again once it is unpinned.
+func Test_BpTree_Pool_Pinned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.bp")
+	tree, err := Open(path, 4, WithPageSize(1024), WithBufferPool(minPoolPages))
+	require.NoError(t, err)
+	for key := int64(0); key < 1000; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+	}
+	require.NoError(t, tree.Flush())
+	pool := tree.file.pool
+	head := tree.root.BpDataHead()
+
+	// Pin the first data node, then read every node again and again, as a long scan would.
+	items := pool.pin(head)
+	require.Equal(t, int64(0), items[0].Key)
+	for round := 0; round < 3; round++ {
+		require.Len(t, tree.Range(0, 1000), 1000)
+		require.False(t, head.frame.evicted.Load())
+		stats, err := tree.PoolStats()
+		require.NoError(t, err)
+		require.Positive(t, stats.Evictions)
+		require.LessOrEqual(t, stats.Resident, stats.Capacity)
+	}
+
+	// Once unpinned, the data node is evicted like any other.
+	pool.unpin(head)
+	for round := 0; round < 3 && !head.frame.evicted.Load(); round++ {
+		require.Len(t, tree.Range(0, 1000), 1000)
+	}
+	require.True(t, head.frame.evicted.Load())
+	require.NoError(t, tree.Close())
+}
+
+// Test_BpTree_Pool_Snapshot 🧫 takes a snapshot of a tree larger than its buffer pool, changes the tree
+// until the pages of the old data nodes are reused, and reads the snapshot while the tree changes.
+func Test_BpTree_Pool_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.bp")
+	tree, err := Open(path, 4, WithPageSize(1024), WithBufferPool(minPoolPages))
+	require.NoError(t, err)
+	for key := int64(0); key < 1000; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+	}
+	require.NoError(t, tree.Flush())
+	want := collectItems(tree)
+	snap, err := tree.Snapshot()
+	require.NoError(t, err)
+
+	// A writer replaces every value and flushes, while the snapshot is read.
+	done := make(chan error)
+	go func() {
+		for round := 0; round < 5; round++ {
+			for key := int64(0); key < 1000; key++ {
+				if _, _, err := tree.Upsert(key, -key); err != nil {
+					done <- err
+					return
+				}
+			}
+			if err := tree.Flush(); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for round := 0; round < 5; round++ {
+		require.Equal(t, want, snap.Range(0, 1000))
+	}
+	require.NoError(t, <-done)
+
+	// The snapshot still holds the old values, the tree the new ones.
+	require.Equal(t, want, snap.Range(0, 1000))
+	item, found := tree.Get(7)
+	require.True(t, found)
+	require.Equal(t, int64(-7), item.Val)
+	require.NoError(t, tree.Close())
+}
+
+// Test_BpTree_Pool_Parallel 🧫 reads a tree larger than its buffer pool from several goroutines while it is changed
+// and flushed, so that the readers fault the same data nodes in at the same time and the pool evicts between them.
+func Test_BpTree_Pool_Parallel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.bp")
+	tree, err := Open(path, 4, WithPageSize(1024), WithBufferPool(minPoolPages))
+	require.NoError(t, err)
+	for key := int64(0); key < 1000; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+	}
+	require.NoError(t, tree.Flush())
+
+	// The readers look up keys the writer never touches.
+	done := make(chan error, 4)
+	for reader := 0; reader < 4; reader++ {
+		go func(seed int64) {
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < 2000; i++ {
+				key := rng.Int63n(500) * 2
+				if item, found := tree.Get(key); !found || item.Val != key {
+					done <- fmt.Errorf("key %d: %v, %v", key, item, found)
+					return
+				}
+			}
+			done <- nil
+		}(int64(reader))
+	}
+
+	// The writer changes the odd keys and flushes.
+	for round := 0; round < 10; round++ {
+		for key := int64(1); key < 1000; key += 2 {
+			_, _, err = tree.Upsert(key, -key)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tree.Flush())
+	}
+	for reader := 0; reader < 4; reader++ {
+		require.NoError(t, <-done)
+	}
+
+	// The pool is back within its capacity once the readers are done.
+	require.NoError(t, tree.Validate())
+	stats, err := tree.PoolStats()
+	require.NoError(t, err)
+	require.LessOrEqual(t, stats.Resident, stats.Capacity)
+	require.NoError(t, tree.Close())
+}
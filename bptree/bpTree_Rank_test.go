@@ -0,0 +1,95 @@
+package bpTree
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Rank 🧫 inserts and removes random keys, and checks Len, Rank, Select and CountRange
+// against a sorted slice after every few steps.
+func Test_BpTree_Rank(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 16} {
+		for _, opt := range []BpOption{WithUniqueKeys(), WithDuplicates()} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width)))
+			tree := NewBpTree(width, opt)
+
+			var live []int64
+			for step := 0; step < 3000; step++ {
+				// Insert more often than remove, so that the tree grows and shrinks.
+				key := rng.Int63n(500)
+				if rng.Intn(3) > 0 {
+					if tree.Insert(BpItem{Key: key}) == nil {
+						live = append(live, key)
+					}
+				} else if deleted, _, _, err := tree.RemoveValue(BpItem{Key: key}); deleted {
+					require.NoError(t, err)
+					live = slices.Delete(live, slices.Index(live, key), slices.Index(live, key)+1)
+				}
+				if step%50 != 0 {
+					continue
+				}
+
+				// Validate checks the counts of every node.
+				require.NoError(t, tree.Validate(), "width %d, step %d", width, step)
+				sorted := slices.Sorted(slices.Values(live))
+				require.Equal(t, len(sorted), tree.Len())
+
+				// Rank is the position of the first key not less than the key.
+				for probe := int64(-1); probe <= 501; probe += 3 {
+					require.Equal(t, sort.Search(len(sorted), func(i int) bool { return sorted[i] >= probe }), tree.Rank(probe))
+				}
+
+				// Select walks the keys in order.
+				for i, key := range sorted {
+					item, found := tree.Select(i)
+					require.True(t, found)
+					require.Equal(t, key, item.Key)
+				}
+				_, found := tree.Select(len(sorted))
+				require.False(t, found)
+				_, found = tree.Select(-1)
+				require.False(t, found)
+
+				// CountRange counts the half-open range.
+				from, to := rng.Int63n(500), rng.Int63n(500)
+				count := 0
+				for _, key := range sorted {
+					if key >= from && key < to {
+						count++
+					}
+				}
+				require.Equal(t, count, tree.CountRange(from, to))
+			}
+		}
+	}
+}
+
+// Test_BpTree_Rank_Loaded 🧫 checks the counts of bulk loaded trees and trees changed by ApplyBatch.
+func Test_BpTree_Rank_Loaded(t *testing.T) {
+	for _, width := range []int{3, 4, 8} {
+		// A bulk loaded tree counts every item.
+		var items []BpItem
+		for key := int64(0); key < 300; key++ {
+			items = append(items, BpItem{Key: key / 2})
+		}
+		tree := NewBpTree(width)
+		require.NoError(t, tree.BulkLoad(items, 0.7))
+		require.NoError(t, tree.Validate())
+		require.Equal(t, 300, tree.Len())
+		require.Equal(t, 20, tree.Rank(10))
+		require.Equal(t, 4, tree.CountRange(10, 12))
+
+		// A batch keeps the counts right.
+		rng := rand.New(rand.NewSource(int64(width)))
+		stream := signedStream(rng, 400)
+		_, _, err := tree.ApplyBatch(OpsFromSigned(stream[:len(stream)/2]))
+		require.NoError(t, err)
+		require.NoError(t, tree.Validate())
+		require.Equal(t, countSeq(tree), tree.Len())
+	}
+}
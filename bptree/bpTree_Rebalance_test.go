@@ -0,0 +1,192 @@
+package bpTree
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Rebalance 🧫 builds small trees, removes one key and checks how the nodes along its path are repaired.
+func Test_BpTree_Rebalance(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		root  *BpIndex
+		key   int64   // The key that is removed.
+		index []int64 // The index of the root afterward.
+		depth int     // The number of index levels afterward.
+	}{
+		{
+			name:  "empty data node merges into its left neighbor",
+			width: 3,
+			root:  bottom(leaf(1, 2), leaf(3), leaf(4, 5)),
+			key:   3,
+			index: []int64{4},
+			depth: 1,
+		},
+		{
+			name:  "first data node merges with its right neighbor",
+			width: 3,
+			root:  bottom(leaf(1), leaf(2, 3), leaf(4, 5)),
+			key:   1,
+			index: []int64{4},
+			depth: 1,
+		},
+		{
+			name:  "two small data nodes at the root merge",
+			width: 3,
+			root:  bottom(leaf(1), leaf(2, 3)),
+			key:   3,
+			index: []int64{},
+			depth: 1,
+		},
+		{
+			name:  "data node on the right of the root merges into the left",
+			width: 3,
+			root:  bottom(leaf(1, 2), leaf(3)),
+			key:   3,
+			index: []int64{},
+			depth: 1,
+		},
+		{
+			name:  "last item leaves an empty tree",
+			width: 3,
+			root:  bottom(leaf(1)),
+			key:   1,
+			index: []int64{},
+			depth: 1,
+		},
+		{
+			name:  "index node borrows from a full neighbor",
+			width: 3,
+			root:  branch(bottom(leaf(1), leaf(2)), bottom(leaf(3), leaf(4), leaf(5))),
+			key:   2,
+			index: []int64{4},
+			depth: 2,
+		},
+		{
+			name:  "index nodes merge and the root collapses",
+			width: 3,
+			root:  branch(bottom(leaf(1), leaf(2)), bottom(leaf(3), leaf(4))),
+			key:   1,
+			index: []int64{3, 4},
+			depth: 1,
+		},
+		{
+			name:  "right index node merges into the left and the root collapses",
+			width: 3,
+			root:  branch(bottom(leaf(1), leaf(2)), bottom(leaf(3), leaf(4))),
+			key:   4,
+			index: []int64{2, 3},
+			depth: 1,
+		},
+		{
+			name:  "merging cascades up to the root from the right",
+			width: 3,
+			root: branch(
+				branch(bottom(leaf(1), leaf(2)), bottom(leaf(3), leaf(4))),
+				branch(bottom(leaf(5), leaf(6)), bottom(leaf(7), leaf(8))),
+			),
+			key:   8,
+			index: []int64{3, 5},
+			depth: 2,
+		},
+		{
+			name:  "merging cascades up to the root",
+			width: 3,
+			root: branch(
+				branch(bottom(leaf(1), leaf(2)), bottom(leaf(3), leaf(4))),
+				branch(bottom(leaf(5), leaf(6)), bottom(leaf(7), leaf(8))),
+			),
+			key:   1,
+			index: []int64{5, 7},
+			depth: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := treeOf(test.width, test.root)
+			require.NoError(t, tree.Validate())
+			keys := slices.DeleteFunc(snapshotKeys(tree), func(key int64) bool {
+				return key == test.key
+			})
+
+			// The removal keeps every other key and leaves a valid tree.
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: test.key})
+			require.True(t, deleted)
+			require.NoError(t, err)
+			require.NoError(t, tree.Validate())
+			require.Equal(t, keys, append([]int64{}, snapshotKeys(tree)...))
+			require.Equal(t, test.index, tree.root.Index)
+			require.Equal(t, test.depth, depthOf(tree.root))
+
+			// The tree keeps working afterward.
+			for key := int64(11); key <= 30; key++ {
+				require.NoError(t, tree.Insert(BpItem{Key: key}))
+			}
+			for _, key := range keys {
+				deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+				require.True(t, deleted)
+				require.NoError(t, err)
+				require.NoError(t, tree.Validate())
+			}
+		})
+	}
+}
+
+// leaf creates a data node with the keys.
+func leaf(keys ...int64) (data *BpData) {
+	data = &BpData{}
+	for _, key := range keys {
+		data.Items = append(data.Items, BpItem{Key: key})
+	}
+	return
+}
+
+// bottom creates a bottom index node over the data nodes, the index keys are the first keys of the data nodes.
+func bottom(dataNodes ...*BpData) (inode *BpIndex) {
+	inode = &BpIndex{Index: []int64{}, DataNodes: dataNodes}
+	for _, data := range dataNodes[1:] {
+		var key int64
+		if len(data.Items) > 0 {
+			key = data.Items[0].Key
+		}
+		inode.Index = append(inode.Index, key)
+	}
+	return
+}
+
+// branch creates an index node over the index nodes, the index keys are the edge values of the children.
+func branch(indexNodes ...*BpIndex) (inode *BpIndex) {
+	inode = &BpIndex{Index: []int64{}, IndexNodes: indexNodes}
+	for _, child := range indexNodes[1:] {
+		inode.Index = append(inode.Index, child.edgeValue())
+	}
+	return
+}
+
+// treeOf creates a tree with the root and links its data nodes.
+func treeOf(width int, root *BpIndex) (tree *BpTree) {
+	tree = NewBpTree(width)
+	tree.root = root
+	var previous *BpData
+	root.eachData(func(data *BpData) {
+		data.Previous = previous
+		if previous != nil {
+			previous.Next = data
+		}
+		previous = data
+	})
+	root.recountAll(tree.cfg)
+	return
+}
+
+// depthOf counts the index levels down to the data nodes.
+func depthOf(inode *BpIndex) (depth int) {
+	for depth = 1; len(inode.IndexNodes) > 0; depth++ {
+		inode = inode.IndexNodes[0]
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package bpTree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Get_Contains 🧫 checks point lookups against a shadow map while inserting and deleting.
+func Test_BpTree_Get_Contains(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7, 8} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(int64(width)))
+
+		tree := NewBpTree(width)
+		shadow := map[int64]bool{}
+
+		// Insert unique random keys; only the odd ones, so that the even ones are always missing.
+		keys := make([]int64, 0, 1000)
+		for _, n := range rng.Perm(1000) {
+			key := int64(n)*2 + 1
+			tree.InsertValue(BpItem{Key: key, Val: key * 10})
+			shadow[key] = true
+			keys = append(keys, key)
+		}
+
+		// Every key in the shadow map must be found.
+		for key := int64(0); key <= 2000; key++ {
+			item, found := tree.Get(key)
+			require.Equal(t, shadow[key], found, "width %d, key %d", width, key)
+			require.Equal(t, shadow[key], tree.Contains(key), "width %d, key %d", width, key)
+			if found {
+				require.Equal(t, key, item.Key)
+				require.Equal(t, key*10, item.Val)
+			}
+		}
+
+		// Remove half of the keys, then check again.
+		shuffleSlice(keys, rng)
+		for _, key := range keys[:len(keys)/2] {
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+			require.True(t, deleted, "width %d, key %d", width, key)
+			require.NoError(t, err)
+			delete(shadow, key)
+		}
+
+		for key := int64(0); key <= 2000; key++ {
+			_, found := tree.Get(key)
+			require.Equal(t, shadow[key], found, "width %d, key %d", width, key)
+		}
+	}
+
+	// Duplicates spanning several data nodes are found from the leftmost one.
+	tree := NewBpTree(3)
+	for i := 0; i < 20; i++ {
+		tree.InsertValue(BpItem{Key: 5, Val: i})
+		tree.InsertValue(BpItem{Key: int64(i + 10)})
+	}
+	require.True(t, tree.Contains(5))
+	require.False(t, tree.Contains(6))
+
+	// Masked items are skipped.
+	tree = NewBpTree(4)
+	tree.InsertValue(BpItem{Key: 1, Val: "first"})
+	tree.InsertValue(BpItem{Key: 1, Val: "second"})
+	tree.root.DataNodes[0].Items[0].Mask = true
+	item, found := tree.Get(1)
+	require.True(t, found)
+	require.Equal(t, tree.root.DataNodes[0].Items[1].Val, item.Val)
+
+	tree.root.DataNodes[0].Items[1].Mask = true
+	require.False(t, tree.Contains(1))
+
+	// An empty tree finds nothing.
+	require.False(t, NewBpTree(3).Contains(1))
+}
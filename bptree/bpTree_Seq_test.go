@@ -0,0 +1,89 @@
+package bpTree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Seq 🧫 checks the range-over-func iterators, and that the loop body can use the tree.
+func Test_BpTree_Seq(t *testing.T) {
+	tree := NewBpTree(4)
+	for key := int64(100); key >= 1; key-- {
+		tree.InsertValue(BpItem{Key: key, Val: key * 10})
+	}
+
+	// All walks in ascending order.
+	var want int64 = 1
+	for key, val := range tree.All() {
+		require.Equal(t, want, key)
+		require.Equal(t, key*10, val)
+		want++
+	}
+	require.Equal(t, int64(101), want)
+
+	// Backward walks in descending order.
+	want = 100
+	for key := range tree.Backward() {
+		require.Equal(t, want, key)
+		want--
+	}
+	require.Equal(t, int64(0), want)
+
+	// Ascend stops before the upper bound.
+	var keys []int64
+	for key := range tree.Ascend(40, 45) {
+		keys = append(keys, key)
+	}
+	require.Equal(t, []int64{40, 41, 42, 43, 44}, keys)
+
+	// Breaking early must release the lock, otherwise the insert below would deadlock.
+	for key := range tree.All() {
+		if key == 3 {
+			break
+		}
+	}
+	for range tree.Backward() {
+		break
+	}
+	for range tree.Ascend(10, 20) {
+		break
+	}
+	tree.InsertValue(BpItem{Key: 101})
+	require.True(t, tree.Contains(101))
+
+	// A panic in the loop body releases the lock too.
+	require.Panics(t, func() {
+		for range tree.All() {
+			panic("stop")
+		}
+	})
+	tree.InsertValue(BpItem{Key: 102})
+
+	// The loop body may use the tree and even modify it, the lock is not held while an item is yielded.
+	// Removing the current item does not disturb the walk, it goes on with the successor.
+	seen := 0
+	for key := range tree.All() {
+		_, found := tree.Get(key)
+		require.True(t, found)
+		if key%2 == 0 {
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+			require.True(t, deleted)
+			require.NoError(t, err)
+		}
+		seen++
+	}
+	require.Equal(t, 102, seen)
+	require.Equal(t, 51, countSeq(tree))
+	seen = 0
+	for key := range tree.Backward() {
+		tree.InsertValue(BpItem{Key: key + 1000})
+		seen++
+	}
+	require.Equal(t, 51, seen)
+
+	// An empty tree yields nothing.
+	for range NewBpTree(3).All() {
+		t.Fatal("an empty tree should yield nothing")
+	}
+}
@@ -0,0 +1,128 @@
+package bpTree
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Snapshot 🧫 takes snapshots during random insertions and deletions,
+// and checks that every snapshot keeps the keys it had when it was taken.
+func Test_BpTree_Snapshot(t *testing.T) {
+	for seed := int64(0); seed < 3; seed++ {
+		for _, width := range []int{3, 4, 5, 7, 16} {
+			for _, opt := range []BpOption{WithUniqueKeys(), WithDuplicates()} {
+				// Use a fixed seed so that the result can be reproduced.
+				rng := rand.New(rand.NewSource(seed))
+				tree := NewBpTree(width, opt, WithCopyOnWrite())
+
+				var snaps []*BpSnapshot
+				var expected [][]int64
+				var live []int64
+				for step := 0; step < 3000; step++ {
+					// Insert more often than remove, so that the tree grows and shrinks.
+					key := rng.Int63n(300)
+					if rng.Intn(3) > 0 {
+						if tree.Insert(BpItem{Key: key, Val: key}) == nil {
+							live = append(live, key)
+						}
+					} else if deleted, _, _, err := tree.RemoveValue(BpItem{Key: key}); deleted {
+						require.NoError(t, err)
+						live = slices.Delete(live, slices.Index(live, key), slices.Index(live, key)+1)
+					}
+
+					// Take a snapshot from time to time.
+					if step%100 == 0 {
+						snap, err := tree.Snapshot()
+						require.NoError(t, err)
+						snaps = append(snaps, snap)
+						expected = append(expected, slices.Sorted(slices.Values(live)))
+					}
+				}
+
+				// The live tree is still sound and holds the live keys.
+				require.NoError(t, tree.Validate())
+				require.Equal(t, slices.Sorted(slices.Values(live)), snapshotKeys(tree))
+
+				// Every snapshot still holds the keys of its own time.
+				for i, snap := range snaps {
+					var keys []int64
+					for key, val := range snap.All() {
+						require.Equal(t, key, val)
+						keys = append(keys, key)
+					}
+					require.Equal(t, expected[i], keys, "seed %d, width %d, snapshot %d", seed, width, i)
+
+					// Get and Range see the same keys.
+					for key := int64(0); key < 300; key += 7 {
+						_, found := snap.Get(key)
+						require.Equal(t, slices.Contains(expected[i], key), found)
+					}
+					from, to := int64(50), int64(120)
+					count := 0
+					for _, key := range expected[i] {
+						if key >= from && key < to {
+							count++
+						}
+					}
+					require.Len(t, snap.Range(from, to), count)
+				}
+			}
+		}
+	}
+}
+
+// Test_BpTree_Snapshot_Parallel 🧫 reads snapshots while the tree is being modified.
+// Run it with -race to check that the snapshots never see a modification.
+func Test_BpTree_Snapshot_Parallel(t *testing.T) {
+	tree := NewBpTree(8, WithCopyOnWrite())
+	for key := int64(0); key < 1000; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key}))
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				// The snapshot is read without the tree lock.
+				snap, err := tree.Snapshot()
+				require.NoError(t, err)
+				var keys []int64
+				for key := range snap.All() {
+					keys = append(keys, key)
+				}
+				require.True(t, slices.IsSorted(keys))
+				for _, key := range keys {
+					_, found := snap.Get(key)
+					require.True(t, found)
+				}
+			}
+		}()
+	}
+
+	// The writer keeps inserting and removing at the same time.
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 3000; i++ {
+		key := rng.Int63n(2000)
+		if i%2 == 0 {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+		} else {
+			tree.RemoveValue(BpItem{Key: key})
+		}
+	}
+	wg.Wait()
+	require.NoError(t, tree.Validate())
+}
+
+// Test_BpTree_Snapshot_Off 🧫 checks that a tree without copy-on-write refuses to take snapshots.
+func Test_BpTree_Snapshot_Off(t *testing.T) {
+	tree := NewBpTree(4)
+	snap, err := tree.Snapshot()
+	require.ErrorIs(t, err, ErrCopyOnWriteOff)
+	require.Nil(t, snap)
+}
@@ -0,0 +1,88 @@
+package bpTree
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countTracer counts the events by kind and keeps the last event of each kind.
+type countTracer struct {
+	counts map[TraceKind]int
+	last   map[TraceKind]TraceEvent
+}
+
+// Trace records the event.
+func (tracer *countTracer) Trace(event TraceEvent) {
+	tracer.counts[event.Kind]++
+	tracer.last[event.Kind] = event
+}
+
+// Test_BpTree_Trace 🧫 counts the structural events of random insertions and deletions.
+func Test_BpTree_Trace(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7} {
+		// Use a fixed seed so that the result can be reproduced.
+		rng := rand.New(rand.NewSource(int64(width)))
+		tracer := &countTracer{counts: map[TraceKind]int{}, last: map[TraceKind]TraceEvent{}}
+		tree := NewBpTree(width)
+		tree.SetTracer(tracer)
+
+		keys := make([]int64, 500)
+		for i := range keys {
+			keys[i] = int64(i)
+		}
+		shuffleSlice(keys, rng)
+
+		// Insertions split the nodes and promote the root.
+		for _, key := range keys {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+		}
+		require.Positive(t, tracer.counts[TraceSplit])
+		require.Positive(t, tracer.counts[TraceRootPromotion])
+		require.Zero(t, tracer.counts[TraceMerge]+tracer.counts[TraceRootCollapse])
+
+		// The keys of the last root promotion are the index of the root at that time, later insertions only add keys to it.
+		require.NotEmpty(t, tracer.last[TraceRootPromotion].Keys)
+		require.Subset(t, tree.root.Index, tracer.last[TraceRootPromotion].Keys)
+		require.False(t, tracer.last[TraceRootPromotion].Data)
+
+		// The keys of an event are a copy, changing them leaves the tree untouched.
+		for i := range tracer.last[TraceRootPromotion].Keys {
+			tracer.last[TraceRootPromotion].Keys[i] = -1
+		}
+		require.NoError(t, tree.Validate())
+
+		// Deletions borrow, merge and collapse the root until one data node is left.
+		shuffleSlice(keys, rng)
+		for _, key := range keys {
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+			require.True(t, deleted)
+			require.NoError(t, err)
+		}
+		require.Positive(t, tracer.counts[TraceBorrowLeft]+tracer.counts[TraceBorrowRight], "width %d", width)
+		require.Positive(t, tracer.counts[TraceMerge])
+		require.Positive(t, tracer.counts[TraceRootCollapse])
+
+		// Without a tracer, nothing is reported any more.
+		tree.SetTracer(nil)
+		before := tracer.counts[TraceSplit]
+		for _, key := range keys {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+		}
+		require.Equal(t, before, tracer.counts[TraceSplit])
+		require.NoError(t, tree.Validate())
+	}
+}
+
+// Test_BpTree_TraceKind 🧫 checks the names of the event kinds.
+func Test_BpTree_TraceKind(t *testing.T) {
+	require.Equal(t, "split", TraceSplit.String())
+	require.Equal(t, "borrow-left", TraceBorrowLeft.String())
+	require.Equal(t, "borrow-right", TraceBorrowRight.String())
+	require.Equal(t, "merge", TraceMerge.String())
+	require.Equal(t, "root-promotion", TraceRootPromotion.String())
+	require.Equal(t, "root-collapse", TraceRootCollapse.String())
+	require.Equal(t, "mask", TraceMask.String())
+	require.Equal(t, "unknown", TraceKind(0).String())
+}
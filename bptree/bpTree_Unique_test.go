@@ -0,0 +1,165 @@
+package bpTree
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_UniqueKeys 🧫 checks that a tree created with WithUniqueKeys rejects existing keys.
+func Test_BpTree_UniqueKeys(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 7} {
+		tree := NewBpTree(width, WithUniqueKeys())
+		for key := int64(0); key < 200; key++ {
+			require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+		}
+
+		// Inserting an existing key fails and keeps the old value.
+		for key := int64(0); key < 200; key += 7 {
+			require.ErrorIs(t, tree.Insert(BpItem{Key: key, Val: -key}), ErrDuplicateKey)
+		}
+		require.Equal(t, 200, countSeq(tree))
+		item, found := tree.Get(21)
+		require.True(t, found)
+		require.Equal(t, int64(21), item.Val)
+
+		// After the key is removed, it can be inserted again.
+		deleted, _, _, err := tree.RemoveValue(BpItem{Key: 21})
+		require.True(t, deleted)
+		require.NoError(t, err)
+		deleted, _, _, _ = tree.RemoveValue(BpItem{Key: 21})
+		require.False(t, deleted)
+		require.NoError(t, tree.Insert(BpItem{Key: 21, Val: "again"}))
+		item, _ = tree.Get(21)
+		require.Equal(t, "again", item.Val)
+	}
+}
+
+// Test_BpTree_Upsert 🧫 checks Upsert and InsertIfAbsent in both modes.
+func Test_BpTree_Upsert(t *testing.T) {
+	for _, opt := range []BpOption{WithUniqueKeys(), WithDuplicates()} {
+		tree := NewBpTreeG[int, string](4, opt)
+
+		// Upsert inserts new keys.
+		for key := 0; key < 100; key++ {
+			old, replaced, err := tree.Upsert(key, "first")
+			require.NoError(t, err)
+			require.False(t, replaced)
+			require.Equal(t, "", old)
+		}
+
+		// Upsert replaces the value of existing keys instead of adding duplicates.
+		for key := 0; key < 100; key += 2 {
+			old, replaced, err := tree.Upsert(key, "second")
+			require.NoError(t, err)
+			require.True(t, replaced)
+			require.Equal(t, "first", old)
+		}
+		count := 0
+		for key, val := range tree.All() {
+			if key%2 == 0 {
+				require.Equal(t, "second", val)
+			} else {
+				require.Equal(t, "first", val)
+			}
+			count++
+		}
+		require.Equal(t, 100, count)
+
+		// InsertIfAbsent only adds missing keys.
+		inserted, err := tree.InsertIfAbsent(50, "third")
+		require.NoError(t, err)
+		require.False(t, inserted)
+		inserted, err = tree.InsertIfAbsent(100, "third")
+		require.NoError(t, err)
+		require.True(t, inserted)
+		item, found := tree.Get(50)
+		require.True(t, found)
+		require.Equal(t, "second", item.Val)
+		item, found = tree.Get(100)
+		require.True(t, found)
+		require.Equal(t, "third", item.Val)
+	}
+}
+
+// Test_BpTree_Duplicates_Remove 🧫 removes heavily duplicated keys one by one.
+// The index may be stale after a deletion, and the remaining duplicates must still be found.
+func Test_BpTree_Duplicates_Remove(t *testing.T) {
+	for seed := int64(0); seed < 30; seed++ {
+		for _, width := range []int{3, 4, 5, 6, 7, 8, 11} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(seed))
+
+			// Every key appears 1 to 10 times.
+			tree := NewBpTree(width, WithDuplicates())
+			var keys []int64
+			for key := int64(0); key < 100; key++ {
+				for n := rng.Intn(10); n >= 0; n-- {
+					keys = append(keys, key)
+				}
+			}
+			shuffleSlice(keys, rng)
+			for _, key := range keys {
+				require.NoError(t, tree.Insert(BpItem{Key: key}))
+			}
+
+			// Remove them in another random order and check the rest from time to time.
+			shuffleSlice(keys, rng)
+			for i, key := range keys {
+				deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+				require.True(t, deleted, "seed %d, width %d, step %d, key %d", seed, width, i, key)
+				require.NoError(t, err)
+
+				if i%50 == 0 {
+					rest := slices.Clone(keys[i+1:])
+					slices.Sort(rest)
+					require.True(t, slices.Equal(rest, snapshotKeys(tree)), "seed %d, width %d, step %d", seed, width, i)
+				}
+			}
+			require.Empty(t, snapshotKeys(tree))
+		}
+	}
+}
+
+// Test_BpTree_RemoveValue_Modes 🧫 checks which item RemoveValue takes in every mode.
+// With unique keys it is the only item of the key, with duplicates it is the newest one.
+func Test_BpTree_RemoveValue_Modes(t *testing.T) {
+	for _, unique := range []bool{false, true} {
+		for _, lazy := range []bool{false, true} {
+			opts := []BpOption{WithDuplicates()}
+			if unique {
+				opts = []BpOption{WithUniqueKeys()}
+			}
+			if lazy {
+				opts = append(opts, WithLazyDeletion())
+			}
+			tree := NewBpTree(3, opts...)
+			for key := int64(0); key < 20; key++ {
+				require.NoError(t, tree.Insert(BpItem{Key: key, Val: "old"}))
+			}
+			err := tree.Insert(BpItem{Key: 7, Val: "new"})
+			require.Equal(t, unique, errors.Is(err, ErrDuplicateKey))
+
+			// The first removal takes the newest item, or the only one.
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: 7})
+			require.NoError(t, err)
+			require.True(t, deleted)
+			item, found := tree.Get(7)
+			require.Equal(t, !unique, found, "unique %v, lazy %v", unique, lazy)
+			if found {
+				require.Equal(t, "old", item.Val)
+			}
+
+			// The second removal takes the old item in duplicate mode, and nothing with unique keys.
+			deleted, _, _, err = tree.RemoveValue(BpItem{Key: 7})
+			require.NoError(t, err)
+			require.Equal(t, !unique, deleted)
+			_, found = tree.Get(7)
+			require.False(t, found)
+			require.NoError(t, tree.Validate())
+		}
+	}
+}
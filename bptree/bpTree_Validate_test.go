@@ -0,0 +1,187 @@
+package bpTree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Validate 🧫 calls Validate after every insertion and deletion.
+func Test_BpTree_Validate(t *testing.T) {
+	for seed := int64(0); seed < 3; seed++ {
+		for _, width := range []int{3, 4, 5, 6, 7, 8, 11} {
+			for _, duplicates := range []bool{false, true} {
+				// Use a fixed seed so that the result can be reproduced.
+				rng := rand.New(rand.NewSource(seed))
+
+				// Prepare unique keys, or keys that appear up to 4 times.
+				var keys []int64
+				for key := int64(0); key < 300; key++ {
+					keys = append(keys, key)
+					for n := rng.Intn(4); duplicates && n > 0; n-- {
+						keys = append(keys, key)
+					}
+				}
+
+				tree := NewBpTree(width)
+				require.NoError(t, tree.Validate())
+
+				shuffleSlice(keys, rng)
+				for _, key := range keys {
+					require.NoError(t, tree.Insert(BpItem{Key: key}))
+					require.NoError(t, tree.Validate(), "seed %d, width %d, insert %d", seed, width, key)
+				}
+
+				shuffleSlice(keys, rng)
+				for _, key := range keys {
+					deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+					require.True(t, deleted)
+					require.NoError(t, err)
+					require.NoError(t, tree.Validate(), "seed %d, width %d, remove %d", seed, width, key)
+				}
+			}
+		}
+	}
+}
+
+// Test_BpTree_Validate_Corrupted 🧫 breaks the tree on purpose and checks the reported path.
+func Test_BpTree_Validate_Corrupted(t *testing.T) {
+	// newTree builds a tree with several levels of index nodes.
+	newTree := func() *BpTree {
+		tree := NewBpTree(3)
+		for key := int64(1); key <= 40; key++ {
+			require.NoError(t, tree.Insert(BpItem{Key: key}))
+		}
+		require.NoError(t, tree.Validate())
+		return tree
+	}
+
+	// leftmost descends to the leftmost bottom index node and returns it with its path.
+	leftmost := func(root *BpIndex) (node *BpIndex, path string) {
+		node, path = root, "root"
+		for len(node.IndexNodes) > 0 {
+			node, path = node.IndexNodes[0], path+".IndexNodes[0]"
+		}
+		return
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(root *BpIndex) (path string)
+	}{
+		{
+			name: "stale index key",
+			corrupt: func(root *BpIndex) (path string) {
+				root.Index[0]--
+				return "root"
+			},
+		},
+		{
+			name: "missing index key",
+			corrupt: func(root *BpIndex) (path string) {
+				node, path := leftmost(root)
+				node.Index = node.Index[:len(node.Index)-1]
+				return path
+			},
+		},
+		{
+			name: "unsorted items",
+			corrupt: func(root *BpIndex) (path string) {
+				node, path := leftmost(root)
+				node.DataNodes[0].Items = append(node.DataNodes[0].Items, BpItem{Key: 0})
+				return path + ".DataNodes[0]"
+			},
+		},
+		{
+			name: "broken link",
+			corrupt: func(root *BpIndex) (path string) {
+				node, path := leftmost(root)
+				node.DataNodes[1].Previous = nil
+				return path + ".DataNodes[1]"
+			},
+		},
+		{
+			name: "different depth",
+			corrupt: func(root *BpIndex) (path string) {
+				// Replace the rightmost index node above the bottom with its last child, one level is lost there.
+				parent, path := root, "root"
+				last := len(parent.IndexNodes) - 1
+				for len(parent.IndexNodes[last].IndexNodes[0].IndexNodes) > 0 {
+					parent, path = parent.IndexNodes[last], fmt.Sprintf("%s.IndexNodes[%d]", path, last)
+					last = len(parent.IndexNodes) - 1
+				}
+				child := parent.IndexNodes[last]
+				parent.IndexNodes[last] = child.IndexNodes[len(child.IndexNodes)-1]
+				return fmt.Sprintf("%s.IndexNodes[%d].DataNodes[0]", path, last)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := newTree()
+			path := test.corrupt(tree.root)
+
+			// The error matches ErrInvalidStructure and points at the broken node.
+			err := tree.Validate()
+			require.ErrorIs(t, err, ErrInvalidStructure)
+			var structure *StructureError
+			require.True(t, errors.As(err, &structure))
+			require.Equal(t, path, structure.Path, err.Error())
+		})
+	}
+}
+
+// Test_BpTree_Validate_Minimum 🧫 checks that the nodes below the root must keep as many entries as a split leaves.
+func Test_BpTree_Validate_Minimum(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		root  *BpIndex
+		path  string // The path of the reported node, empty for a sound tree.
+	}{
+		{
+			name:  "small single data node of the root",
+			width: 5,
+			root:  bottom(leaf(1)),
+		},
+		{
+			name:  "data nodes at the minimum",
+			width: 5,
+			root:  bottom(leaf(1, 2), leaf(3, 4)),
+		},
+		{
+			name:  "data node below the minimum",
+			width: 5,
+			root:  bottom(leaf(1, 2), leaf(3)),
+			path:  "root.DataNodes[1]",
+		},
+		{
+			name:  "index node below the minimum",
+			width: 5,
+			root:  branch(bottom(leaf(1, 2), leaf(3, 4)), bottom(leaf(5, 6), leaf(7, 8), leaf(9, 10))),
+			path:  "root.IndexNodes[0]",
+		},
+		{
+			name:  "root with two children",
+			width: 5,
+			root:  branch(bottom(leaf(1, 2), leaf(3, 4), leaf(5, 6)), bottom(leaf(7, 8), leaf(9, 10), leaf(11, 12))),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := treeOf(test.width, test.root).Validate()
+			if test.path == "" {
+				require.NoError(t, err)
+				return
+			}
+			var structure *StructureError
+			require.True(t, errors.As(err, &structure), "%v", err)
+			require.Equal(t, test.path, structure.Path, err.Error())
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package bpTree
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/panhongrainbow/go-algorithm/utilhub"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_WAL 🧫 changes a tree with a write-ahead log under every policy, with every kind of modification,
+// crashes it without Close, and checks that Open brings back every change, the ones before the last Flush
+// and the ones after it.
+func Test_BpTree_WAL(t *testing.T) {
+	for _, policy := range []SyncPolicy{SyncAlways, SyncInterval, SyncNever} {
+		for _, opts := range [][]BpOption{{WithUniqueKeys()}, {WithDuplicates(), WithLazyDeletion()}} {
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(policy)))
+			path := filepath.Join(t.TempDir(), "tree.bp")
+			memory := NewBpTree(4, opts...)
+			for round := 0; round < 6; round++ {
+				tree, err := Open(path, 4, append(opts, WithWriteAheadLog(policy, time.Millisecond))...)
+				require.NoError(t, err)
+				require.NoError(t, tree.Validate())
+				require.Equal(t, collectItems(memory), collectItems(tree))
+
+				for i := 0; i < 300; i++ {
+					item := BpItem{Key: rng.Int63n(200), Val: int64(i)}
+					match := func(val any) bool { return val.(int64)%2 == 0 }
+					switch op := rng.Intn(20); {
+					case op < 8:
+						require.Equal(t, memory.Insert(item), tree.Insert(item))
+					case op < 11:
+						deleted, _, _, _ := memory.RemoveValue(item)
+						deletedToo, _, _, err := tree.RemoveValue(item)
+						require.NoError(t, err)
+						require.Equal(t, deleted, deletedToo)
+					case op == 11:
+						old, replaced, err := memory.Upsert(item.Key, item.Val)
+						require.NoError(t, err)
+						oldToo, replacedToo, err := tree.Upsert(item.Key, item.Val)
+						require.NoError(t, err)
+						require.Equal(t, old, oldToo)
+						require.Equal(t, replaced, replacedToo)
+					case op == 12:
+						inserted, err := memory.InsertIfAbsent(item.Key, item.Val)
+						require.NoError(t, err)
+						insertedToo, err := tree.InsertIfAbsent(item.Key, item.Val)
+						require.NoError(t, err)
+						require.Equal(t, inserted, insertedToo)
+					case op == 13:
+						to := item.Key + rng.Int63n(8)
+						deleted, err := memory.DeleteRange(item.Key, to)
+						require.NoError(t, err)
+						deletedToo, err := tree.DeleteRange(item.Key, to)
+						require.NoError(t, err)
+						require.Equal(t, deleted, deletedToo)
+					case op == 14:
+						popped, found, err := memory.PopMin()
+						require.NoError(t, err)
+						poppedToo, foundToo, err := tree.PopMin()
+						require.NoError(t, err)
+						require.Equal(t, popped, poppedToo)
+						require.Equal(t, found, foundToo)
+						popped, found, err = memory.PopMax()
+						require.NoError(t, err)
+						poppedToo, foundToo, err = tree.PopMax()
+						require.NoError(t, err)
+						require.Equal(t, popped, poppedToo)
+						require.Equal(t, found, foundToo)
+					case op == 15:
+						var ops []Op
+						for j := 0; j < 6; j++ {
+							ops = append(ops, Op{Kind: OpKind(1 + rng.Intn(2)), Item: BpItem{Key: rng.Int63n(200), Val: int64(i)}})
+						}
+						inserted, deleted, err := memory.ApplyBatch(ops)
+						require.NoError(t, err)
+						insertedToo, deletedToo, err := tree.ApplyBatch(ops)
+						require.NoError(t, err)
+						require.Equal(t, []int{inserted, deleted}, []int{insertedToo, deletedToo})
+					case op == 16:
+						removed, found, err := memory.RemoveOldest(item.Key)
+						require.NoError(t, err)
+						removedToo, foundToo, err := tree.RemoveOldest(item.Key)
+						require.NoError(t, err)
+						require.Equal(t, removed, removedToo)
+						require.Equal(t, found, foundToo)
+						removed, found, err = memory.RemoveNewest(item.Key)
+						require.NoError(t, err)
+						removedToo, foundToo, err = tree.RemoveNewest(item.Key)
+						require.NoError(t, err)
+						require.Equal(t, removed, removedToo)
+						require.Equal(t, found, foundToo)
+					case op == 17:
+						count, err := memory.RemoveIf(item.Key, match)
+						require.NoError(t, err)
+						countToo, err := tree.RemoveIf(item.Key, match)
+						require.NoError(t, err)
+						require.Equal(t, count, countToo)
+						removed, found, err := memory.RemoveExact(item.Key+1, int64(i-1))
+						require.NoError(t, err)
+						removedToo, foundToo, err := tree.RemoveExact(item.Key+1, int64(i-1))
+						require.NoError(t, err)
+						require.Equal(t, removed, removedToo)
+						require.Equal(t, found, foundToo)
+					case op == 18:
+						removed, err := memory.Compact()
+						require.NoError(t, err)
+						removedToo, err := tree.Compact()
+						require.NoError(t, err)
+						require.Equal(t, removed, removedToo)
+					case i%100 == 50:
+						// Load every other item again now and then.
+						var items []BpItem
+						for j, item := range collectItems(memory) {
+							if j%2 == 0 {
+								items = append(items, item)
+							}
+						}
+						require.NoError(t, memory.BulkLoad(items, 0.7))
+						require.NoError(t, tree.BulkLoad(items, 0.7))
+					}
+					// Flush now and then, the log starts over in a new segment.
+					if i == 100 && round%2 == 0 {
+						require.NoError(t, tree.Flush())
+					}
+				}
+				crash(t, tree)
+
+				// Only the segment of the version in the file is left.
+				segments, err := filepath.Glob(path + ".wal.*")
+				require.NoError(t, err)
+				require.Len(t, segments, 1)
+			}
+		}
+	}
+}
+
+// Test_BpTree_WAL_TornTail 🧫 cuts the log at every byte offset, as a crash in the middle of a write would,
+// and checks that Open replays the intact records, cuts off the torn one, and appends after them.
+func Test_BpTree_WAL_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.bp")
+	tree, err := Open(path, 3, WithWriteAheadLog(SyncNever, 0))
+	require.NoError(t, err)
+
+	// Keep the items after every record, states[n] is the tree with the first n records replayed.
+	rng := rand.New(rand.NewSource(1))
+	states := [][]BpItem{nil}
+	for i := 0; i < 60; i++ {
+		item := BpItem{Key: rng.Int63n(20), Val: "row"}
+		if i%4 == 3 {
+			_, _, _, err = tree.RemoveValue(item)
+		} else {
+			err = tree.Insert(item)
+		}
+		require.NoError(t, err)
+		states = append(states, collectItems(tree))
+	}
+	segment := filepath.Base(segmentPath(path, tree.file.header.seq))
+	crash(t, tree)
+	page, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// Read the log with the chunked reader, and find where every record ends.
+	chunks, errs := utilhub.FileNode{}.Goto(dir).ReadBytesInChunks(segment, 100)
+	var log []byte
+	for chunk := range chunks {
+		log = append(log, chunk...)
+	}
+	if err = <-errs; !errors.Is(err, io.EOF) {
+		require.NoError(t, err)
+	}
+	var ends []int
+	for end := 0; end < len(log); {
+		end += walHeaderSize + int(tree.file.header.order.Uint32(log[end:]))
+		ends = append(ends, end)
+	}
+	require.Len(t, ends, 60)
+	require.Equal(t, len(log), ends[len(ends)-1])
+
+	reopen := func(t *testing.T, cut []byte) (tree *BpTree, size int64) {
+		crashed := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(crashed, "tree.bp"), page, filePermission))
+		require.NoError(t, os.WriteFile(filepath.Join(crashed, segment), cut, filePermission))
+		tree, err := Open(filepath.Join(crashed, "tree.bp"), 3, WithWriteAheadLog(SyncNever, 0))
+		require.NoError(t, err)
+		info, err := os.Stat(filepath.Join(crashed, segment))
+		require.NoError(t, err)
+		return tree, info.Size()
+	}
+
+	for offset := 0; offset <= len(log); offset++ {
+		// The records that end before the cut are replayed, the rest is cut off.
+		intact := 0
+		for intact < len(ends) && ends[intact] <= offset {
+			intact++
+		}
+		tree, size := reopen(t, log[:offset])
+		require.NoError(t, tree.Validate(), "offset %d", offset)
+		require.Equal(t, states[intact], collectItems(tree), "offset %d", offset)
+		if intact == 0 {
+			require.Zero(t, size, "offset %d", offset)
+		} else {
+			require.Equal(t, int64(ends[intact-1]), size, "offset %d", offset)
+		}
+
+		// The new records follow the intact ones, and are replayed next time.
+		if offset%37 == 0 {
+			require.NoError(t, tree.Insert(BpItem{Key: 1000, Val: "new"}))
+			crash(t, tree)
+			tree, err = Open(tree.file.file.Name(), 3)
+			require.NoError(t, err)
+			require.Equal(t, append(states[intact], BpItem{Key: 1000, Val: "new"}), collectItems(tree))
+		}
+		crash(t, tree)
+	}
+
+	// A record with a flipped byte ends the log as well.
+	broken := append([]byte{}, log...)
+	broken[ends[9]+walHeaderSize+1] ^= 0xFF
+	tree, _ = reopen(t, broken)
+	require.Equal(t, states[10], collectItems(tree))
+	crash(t, tree)
+}
+
+// Test_BpTree_WAL_GroupCommit 🧫 checks that the records waiting together are written and synced together,
+// and that concurrent writers all reach the log.
+func Test_BpTree_WAL_GroupCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.bp")
+	tree, err := Open(path, 4, WithWriteAheadLog(SyncAlways, 0))
+	require.NoError(t, err)
+
+	// Three records appended before anyone waits are written at once.
+	var waits []walWait
+	for key := int64(0); key < 3; key++ {
+		wait, err := tree.file.logRecord(walRecord[int64, any]{kind: walInsert, key: key})
+		require.NoError(t, err)
+		require.NoError(t, tree.insert(BpItem{Key: key}))
+		waits = append(waits, wait)
+	}
+	waits[2].wait(&err)
+	require.NoError(t, err)
+	waits[0].wait(&err)
+	require.NoError(t, err)
+	require.Equal(t, 1, tree.file.log.writes)
+
+	// While a write is under way, the writers arriving in the meantime append their records and wait,
+	// then the next writer takes all of them in a single write.
+	log := tree.file.log
+	log.mutex.Lock()
+	log.writing = true
+	log.mutex.Unlock()
+	var group sync.WaitGroup
+	for writer := int64(1); writer <= 8; writer++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			if err := tree.Insert(BpItem{Key: writer}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	require.Eventually(t, func() bool {
+		log.mutex.Lock()
+		defer log.mutex.Unlock()
+		return log.appended == 3+8
+	}, 10*time.Second, time.Millisecond)
+	log.mutex.Lock()
+	log.writing = false
+	log.cond.Broadcast()
+	log.mutex.Unlock()
+	group.Wait()
+	require.Equal(t, 2, log.writes)
+	require.Less(t, log.writes, int(log.appended))
+
+	// Writers in parallel, each waits for its own record and writes at most once.
+	before := log.writes
+	for writer := int64(1); writer <= 8; writer++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for i := int64(0); i < 50; i++ {
+				if err := tree.Insert(BpItem{Key: writer*100 + i}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	group.Wait()
+	require.LessOrEqual(t, log.writes-before, 8*50)
+	crash(t, tree)
+
+	tree, err = Open(path, 4)
+	require.NoError(t, err)
+	require.Equal(t, 3+8+8*50, tree.Len())
+	require.NoError(t, tree.Close())
+}
+
+// Test_BpTree_WAL_Failure 🧫 breaks the log and checks that every modification returns the error,
+// and that the ones whose records cannot be appended leave the tree unchanged.
+func Test_BpTree_WAL_Failure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.bp")
+	tree, err := Open(path, 4, WithDuplicates(), WithLazyDeletion(), WithWriteAheadLog(SyncAlways, 0))
+	require.NoError(t, err)
+	for key := int64(0); key < 100; key++ {
+		require.NoError(t, tree.Insert(BpItem{Key: key, Val: key}))
+	}
+	_, _, _, err = tree.RemoveValue(BpItem{Key: 50})
+	require.NoError(t, err)
+
+	// The write that fails is returned by the modification waiting for it.
+	require.NoError(t, tree.file.log.file.Close())
+	_, _, err = tree.Upsert(1, "new")
+	require.ErrorIs(t, err, os.ErrClosed)
+
+	// From then on no record is appended, and no modification is applied.
+	items, masked := collectItems(tree), tree.Masked()
+	match := func(val any) bool { return true }
+	_, _, err = tree.Upsert(2, "new")
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, err = tree.DeleteRange(10, 20)
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.PopMin()
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.PopMax()
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.RemoveOldest(30)
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.RemoveNewest(30)
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.RemoveMatch(30, match)
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.RemoveExact(30, int64(30))
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, err = tree.RemoveIf(30, match)
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, err = tree.Compact()
+	require.ErrorIs(t, err, os.ErrClosed)
+	require.ErrorIs(t, tree.Insert(BpItem{Key: 200}), os.ErrClosed)
+	_, err = tree.InsertIfAbsent(200, nil)
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, _, err = tree.RemoveValue(BpItem{Key: 40})
+	require.ErrorIs(t, err, os.ErrClosed)
+	_, _, err = tree.ApplyBatch([]Op{{Kind: OpDelete, Item: BpItem{Key: 60}}})
+	require.ErrorIs(t, err, os.ErrClosed)
+	require.ErrorIs(t, tree.BulkLoad(nil, 0.7), os.ErrClosed)
+	require.Equal(t, items, collectItems(tree))
+	require.Equal(t, masked, tree.Masked())
+
+	// Flush writes the changes to the file and starts a new segment, the log takes records again.
+	require.NoError(t, tree.Flush())
+	_, _, err = tree.Upsert(2, "new")
+	require.NoError(t, err)
+	require.NoError(t, tree.Close())
+	tree, err = Open(path, 4)
+	require.NoError(t, err)
+	item, _ := tree.Get(1)
+	require.Equal(t, "new", item.Val)
+	item, _ = tree.Get(2)
+	require.Equal(t, "new", item.Val)
+	require.NoError(t, tree.Close())
+}
+
+// crash closes the files of the tree without flushing it, the same as a crash after the last write.
+func crash(t *testing.T, tree *BpTree) {
+	if tree.file.log != nil {
+		require.NoError(t, tree.file.log.close())
+	}
+	require.NoError(t, tree.file.file.Close())
+}
@@ -0,0 +1,93 @@
+package bpTree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_BpTree_Width_Interleaved 🧫 modifies trees of different widths in turn, each tree keeps its own width.
+func Test_BpTree_Width_Interleaved(t *testing.T) {
+	// Create the trees first; a later tree must not change the width of an earlier one.
+	widths := []int{3, 4, 5, 7, 8, 11}
+	trees := make([]*BpTree, len(widths))
+	for i, width := range widths {
+		trees[i] = NewBpTree(width)
+	}
+	for i, width := range widths {
+		require.Equal(t, width, trees[i].cfg.width)
+	}
+
+	// Insert the same keys into every tree in turn.
+	rng := rand.New(rand.NewSource(1))
+	keys := rng.Perm(2000)
+	for _, key := range keys {
+		for _, tree := range trees {
+			tree.InsertValue(BpItem{Key: int64(key)})
+		}
+	}
+
+	// Remove half of the keys from every tree in turn.
+	for _, key := range keys[:1000] {
+		for i, tree := range trees {
+			deleted, _, _, err := tree.RemoveValue(BpItem{Key: int64(key)})
+			require.True(t, deleted, "width %d, key %d", widths[i], key)
+			require.NoError(t, err)
+		}
+	}
+
+	// Every tree holds the same remaining keys.
+	for i, tree := range trees {
+		require.Equal(t, 1000, countSeq(tree), "width %d", widths[i])
+		for _, key := range keys[1000:] {
+			require.True(t, tree.Contains(int64(key)), "width %d, key %d", widths[i], key)
+		}
+	}
+}
+
+// Test_BpTree_Width_Parallel 🧫 runs trees of different widths in parallel subtests.
+func Test_BpTree_Width_Parallel(t *testing.T) {
+	for _, width := range []int{3, 4, 5, 6, 7, 8, 11, 16} {
+		t.Run(fmt.Sprintf("width %d", width), func(t *testing.T) {
+			t.Parallel()
+
+			// Use a fixed seed so that the result can be reproduced.
+			rng := rand.New(rand.NewSource(int64(width)))
+			tree := NewBpTree(width)
+			keys := rng.Perm(3000)
+			for _, key := range keys {
+				tree.InsertValue(BpItem{Key: int64(key)})
+			}
+			require.Equal(t, 3000, countSeq(tree))
+
+			// Remove every key; the tree must become empty.
+			removals := int64Slice(keys)
+			shuffleSlice(removals, rng)
+			for _, key := range removals {
+				deleted, _, _, err := tree.RemoveValue(BpItem{Key: key})
+				require.True(t, deleted, "key %d", key)
+				require.NoError(t, err)
+			}
+			require.Equal(t, 0, countSeq(tree))
+		})
+	}
+}
+
+// countSeq counts the items visited by All.
+func countSeq(tree *BpTree) (count int) {
+	for range tree.All() {
+		count++
+	}
+	return
+}
+
+// int64Slice converts the keys to int64.
+func int64Slice(keys []int) (slice []int64) {
+	slice = make([]int64, len(keys))
+	for i, key := range keys {
+		slice[i] = int64(key)
+	}
+	return
+}
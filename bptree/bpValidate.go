@@ -0,0 +1,185 @@
+package bpTree
+
+import (
+	"fmt"
+)
+
+// ➡️ validate operation
+
+// Validate ensures thread safety, checks the structure of B plus tree, release lock.
+// It returns a *StructureError with the path to the first violating node, or nil when the tree is sound.
+// The checks are:
+//   - every index node has exactly one more child than index keys, and never both index and data children.
+//   - the index keys are sorted, and each key equals the edge value of its right subtree.
+//   - the keys of every subtree lie between the index keys around it.
+//   - all data nodes sit at the same depth.
+//   - no node reaches the width, and no node below the root holds fewer entries than a split leaves behind:
+//     half of the width rounded up, minus one index keys, or half of the width rounded down items.
+//     The masked items of lazy deletion still take their place in a data node, so they count as well.
+//   - the Previous and Next links are symmetric and visit the data nodes in tree order.
+//   - the item count of every index node equals the number of unmasked items below it.
+//
+// (检查整棵树的结构，回传第一个出错节点的路径)
+func (tree *BpTreeG[K, V]) Validate() (err error) {
+	// Acquire a read lock, lookups and scans run in parallel.
+	tree.mutex.RLock()
+
+	// Release the lock to allow other threads to access the tree.
+	defer tree.mutex.RUnlock()
+
+	// Walk through the tree from the root and collect the data nodes in order.
+	v := &bpValidator[K, V]{cfg: tree.cfg, depth: -1}
+	if err = v.node(tree.root, "root", 0, nil, nil); err != nil {
+		return
+	}
+
+	// Check the links between the data nodes.
+	err = v.links()
+
+	// Performing a return.
+	return
+}
+
+// bpValidator keeps the state of one Validate call.
+type bpValidator[K, V any] struct {
+	cfg    *bpConfig[K]
+	depth  int              // The depth of the data nodes, -1 until the first data node is met.
+	leaves []*BpDataG[K, V] // The data nodes in tree order.
+	paths  []string         // The path of each data node.
+}
+
+// node checks an index node and its subtree, all keys in the subtree must lie within [lower, upper].
+// A nil bound means there is no limit on that side.
+func (v *bpValidator[K, V]) node(inode *BpIndexG[K, V], path string, depth int, lower, upper *K) (err error) {
+	// An index node holds either index nodes or data nodes.
+	if len(inode.IndexNodes) > 0 && len(inode.DataNodes) > 0 {
+		return structureError(path, "both IndexNodes and DataNodes have data")
+	}
+	children := len(inode.IndexNodes) + len(inode.DataNodes)
+	if children != len(inode.Index)+1 {
+		return structureError(path, fmt.Sprintf("%d index keys %v for %d child nodes", len(inode.Index), inode.Index, children))
+	}
+
+	// The index must stay below the width, and a non-root node must keep the minimum.
+	if len(inode.Index) >= v.cfg.width {
+		return structureError(path, fmt.Sprintf("%d index keys reach the width %d", len(inode.Index), v.cfg.width))
+	}
+	if depth > 0 && len(inode.Index) < v.cfg.minIndex() {
+		return structureError(path, fmt.Sprintf("%d index keys are fewer than the minimum %d of a non-root index node", len(inode.Index), v.cfg.minIndex()))
+	}
+
+	// The index keys are sorted and within the bounds from the parent.
+	for i, key := range inode.Index {
+		if i > 0 && v.cfg.compare(inode.Index[i-1], key) > 0 {
+			return structureError(path, fmt.Sprintf("index keys %v are not sorted", inode.Index))
+		}
+		if (lower != nil && v.cfg.compare(key, *lower) < 0) || (upper != nil && v.cfg.compare(key, *upper) > 0) {
+			return structureError(path, fmt.Sprintf("index key %v is out of the range of the parent", key))
+		}
+	}
+
+	// Check the children, each one between the index keys around it.
+	count := 0
+	for i := 0; i < children; i++ {
+		low, up := lower, upper
+		if i > 0 {
+			low = &inode.Index[i-1]
+		}
+		if i < len(inode.Index) {
+			up = &inode.Index[i]
+		}
+
+		if len(inode.IndexNodes) > 0 {
+			child := inode.IndexNodes[i]
+			childPath := fmt.Sprintf("%s.IndexNodes[%d]", path, i)
+			if err = v.node(child, childPath, depth+1, low, up); err != nil {
+				return
+			}
+			// The index key equals the edge value of its right subtree.
+			if i > 0 && v.cfg.compare(inode.Index[i-1], child.edgeValue()) != 0 {
+				return structureError(path, fmt.Sprintf("index key %v does not equal the edge value %v of IndexNodes[%d]", inode.Index[i-1], child.edgeValue(), i))
+			}
+			count += child.count
+			continue
+		}
+
+		data := inode.DataNodes[i]
+		dataPath := fmt.Sprintf("%s.DataNodes[%d]", path, i)
+		if err = v.data(data, dataPath, depth+1, low, up, depth == 0 && len(inode.Index) == 0); err != nil {
+			return
+		}
+		count += data.live(v.cfg)
+		// The index key equals the first key of its right data node.
+		if i > 0 && v.cfg.compare(inode.Index[i-1], data.items()[0].Key) != 0 {
+			return structureError(path, fmt.Sprintf("index key %v does not equal the edge value %v of DataNodes[%d]", inode.Index[i-1], data.items()[0].Key, i))
+		}
+	}
+
+	// The item count equals the sum of the children.
+	if inode.count != count {
+		return structureError(path, fmt.Sprintf("the item count %d does not equal the %d items below it", inode.count, count))
+	}
+
+	// Performing a return.
+	return
+}
+
+// data checks a data node, all its keys must be sorted and lie within [lower, upper].
+// Only the single data node of a small root may hold fewer items than the minimum, down to none.
+func (v *bpValidator[K, V]) data(data *BpDataG[K, V], path string, depth int, lower, upper *K, single bool) (err error) {
+	// All data nodes sit at the same depth.
+	if v.depth == -1 {
+		v.depth = depth
+	} else if v.depth != depth {
+		return structureError(path, fmt.Sprintf("the data node is at depth %d, but the others are at depth %d", depth, v.depth))
+	}
+
+	// No data node falls below the minimum or reaches the width.
+	items := data.items()
+	if len(items) < v.cfg.minItems() && !single {
+		return structureError(path, fmt.Sprintf("%d items are fewer than the minimum %d of a data node", len(items), v.cfg.minItems()))
+	}
+	if len(items) >= v.cfg.width {
+		return structureError(path, fmt.Sprintf("%d items reach the width %d", len(items), v.cfg.width))
+	}
+
+	// The keys are sorted and within the bounds from the parent.
+	for i, item := range items {
+		if i > 0 && v.cfg.compare(items[i-1].Key, item.Key) > 0 {
+			return structureError(path, fmt.Sprintf("the key %v at Items[%d] is smaller than the one before it", item.Key, i))
+		}
+		if (lower != nil && v.cfg.compare(item.Key, *lower) < 0) || (upper != nil && v.cfg.compare(item.Key, *upper) > 0) {
+			return structureError(path, fmt.Sprintf("the key %v at Items[%d] is out of the range of the parent", item.Key, i))
+		}
+	}
+
+	// Record the data node for the link check.
+	v.leaves = append(v.leaves, data)
+	v.paths = append(v.paths, path)
+
+	// Performing a return.
+	return
+}
+
+// links checks that the Previous and Next links visit the data nodes in tree order.
+func (v *bpValidator[K, V]) links() (err error) {
+	for i, data := range v.leaves {
+		// The links on both sides point to the neighbors in the tree.
+		var previous, next *BpDataG[K, V]
+		if i > 0 {
+			previous = v.leaves[i-1]
+		}
+		if i < len(v.leaves)-1 {
+			next = v.leaves[i+1]
+		}
+		if data.Previous != previous {
+			return structureError(v.paths[i], "the Previous link does not point to the data node before it")
+		}
+		if data.Next != next {
+			return structureError(v.paths[i], "the Next link does not point to the data node after it")
+		}
+	}
+
+	// Performing a return.
+	return
+}
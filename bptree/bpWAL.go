@@ -0,0 +1,541 @@
+package bpTree
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ➡️ write-ahead log operation
+
+// SyncPolicy decides when the write-ahead log reaches the disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways makes every modification wait until its record is synced to the disk.
+	// The writers waiting at the same time share one fsync. (组提交)
+	SyncAlways SyncPolicy = iota
+	// SyncInterval writes the records at once and syncs them in the background every interval,
+	// a crash of the machine loses the records of the last interval at most.
+	SyncInterval
+	// SyncNever writes the records at once and leaves the syncing to the operating system,
+	// only a crash of the process is survived.
+	SyncNever
+)
+
+// defaultSyncInterval is the interval of SyncInterval when none is given.
+const defaultSyncInterval = 100 * time.Millisecond
+
+// WithWriteAheadLog makes a tree opened by Open log every modification before applying it,
+// so that the changes since the last Flush survive a crash. The interval is only used by SyncInterval,
+// zero takes 100 milliseconds.
+//
+// The log of the file at path is kept in segments named path.wal.<n>, one for each version Flush writes.
+// Open replays the segment of the version in the file, and cuts off a record torn by a crash.
+// Flush removes the segments before its own version, except the ones after the last Checkpoint,
+// which RecoverCheckpoint replays on top of the checkpoint. (保留最后一个检查点之后的日志段)
+// Every modification returns the error of its own record. A modification whose record cannot be appended
+// is not applied. When the write or the sync fails after the lock is released, the change is already applied
+// in memory and only the error is returned. ⚠️ After a failure the log takes no records until the next Flush,
+// which writes the changes to the file, so every modification in between fails. (写入失败后，直到 Flush 前都不接受修改)
+// The segment of the failure misses the failed records, so take a new Checkpoint after that Flush.
+func WithWriteAheadLog(policy SyncPolicy, interval time.Duration) BpOption {
+	return func(opts *bpOptions) {
+		opts.wal, opts.syncInterval = &policy, interval
+	}
+}
+
+// The kinds of log records. A record holds what it takes to repeat the modification on the same tree,
+// so a modification that chooses its items with a function, such as RemoveIf, logs the positions it chose.
+const (
+	walInsert      byte = iota + 1 // An item given to Insert, or to InsertIfAbsent when the key is missing.
+	walRemove                      // A key whose newest item is removed, by RemoveValue or PopMax.
+	walUpsert                      // An item given to Upsert.
+	walRemoveNth                   // A key and the number of its unmasked duplicates skipped before the removed one.
+	walDeleteRange                 // The two keys given to DeleteRange.
+	walBatch                       // The operations of ApplyBatch in key order.
+	walCompact                     // A call of Compact.
+	walLoad                        // The items and the fill factor given to BulkLoad.
+)
+
+// walRecord is a modification in the log, only the fields of its kind are used.
+type walRecord[K, V any] struct {
+	kind  byte
+	key   K               // walInsert, walRemove, walUpsert, walRemoveNth, and the start of walDeleteRange.
+	val   V               // walInsert and walUpsert.
+	to    K               // The end of walDeleteRange.
+	skip  int             // walRemoveNth.
+	ops   []OpG[K, V]     // walBatch.
+	items []BpItemG[K, V] // walLoad.
+	fill  float64         // walLoad.
+}
+
+// walHeaderSize is the length and the checksum before every record.
+const walHeaderSize = 8
+
+// bpLog appends the records of one segment, and makes them durable according to the policy.
+type bpLog struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond // Signaled when a write of the pending records finishes.
+	path     string     // The path of the tree file, the segments are named after it.
+	segment  uint64     // The version of the tree file the current segment starts from.
+	keep     uint64     // The first segment Flush keeps, the one of the last checkpoint; zero without one.
+	file     *os.File
+	policy   SyncPolicy
+	pending  []byte // The records appended but not written yet.
+	size     int64  // The bytes of the records in the segment, the written ones and the pending ones.
+	appended uint64 // The number of the last record appended.
+	written  uint64 // The number of the last record written, and synced with SyncAlways.
+	writing  bool   // A writer is writing the pending records, the others wait for it.
+	writes   int    // The number of writes, every one of them carries one or more records.
+	err      error  // The error of the last write, the segment is not used until the next Flush.
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// walWait is a record that a modification waits for after releasing the lock of the tree.
+type walWait struct {
+	log *bpLog
+	lsn uint64
+}
+
+// wait waits until the record is durable, and keeps the first error.
+func (w *walWait) wait(err *error) {
+	if w.log != nil && *err == nil {
+		*err = w.log.commit(w.lsn)
+	}
+}
+
+// segmentPath returns the path of the segment that starts from the version.
+func segmentPath(path string, segment uint64) string {
+	return path + ".wal." + strconv.FormatUint(segment, 10)
+}
+
+// openLog replays the segment of the version in the file into the tree, and opens it for the new records.
+// Without the policy, the segment is only replayed.
+func (f *bpFile[K, V]) openLog(tree *BpTreeG[K, V], policy *SyncPolicy, interval time.Duration) (err error) {
+	path := segmentPath(f.file.Name(), f.header.seq)
+	if err = f.replay(tree, path); err != nil {
+		return
+	}
+	if policy == nil {
+		return
+	}
+	log := &bpLog{path: f.file.Name(), segment: f.header.seq, keep: f.header.logFrom, policy: *policy}
+	log.cond = sync.NewCond(&log.mutex)
+	if log.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, filePermission); err != nil {
+		return
+	}
+	info, err := log.file.Stat()
+	if err != nil {
+		_ = log.file.Close()
+		return
+	}
+	log.size = info.Size()
+	if log.policy == SyncInterval {
+		log.stop, log.stopped = make(chan struct{}), make(chan struct{})
+		go log.syncEvery(cmp.Or(max(interval, 0), defaultSyncInterval))
+	}
+	f.log = log
+	return
+}
+
+// replay applies the records of the segment to the tree, and cuts the segment after the last intact record.
+func (f *bpFile[K, V]) replay(tree *BpTreeG[K, V], path string) (err error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	// Apply the intact records.
+	intact, err := f.replayFrom(tree, file, 0)
+	if err != nil {
+		return
+	}
+
+	// ⚠️ Cut off the torn tail, the new records are appended after the intact ones.
+	if err = file.Truncate(intact); err != nil {
+		return
+	}
+	return file.Sync()
+}
+
+// replayFrom applies the records of the segment after the offset to the tree, and returns the bytes of the intact ones.
+// The records are read one by one, a record that is cut off or whose checksum does not match ends the log.
+// (遇到残缺的纪录就停止)
+func (f *bpFile[K, V]) replayFrom(tree *BpTreeG[K, V], file *os.File, offset int64) (intact int64, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+	reader, order := bufio.NewReader(file), f.header.order
+	for intact = offset; ; {
+		head := make([]byte, walHeaderSize)
+		if _, err = io.ReadFull(reader, head); err != nil {
+			break
+		}
+		size := int64(order.Uint32(head))
+		if intact+walHeaderSize+size > info.Size() {
+			break
+		}
+		body := make([]byte, size)
+		if _, err = io.ReadFull(reader, body); err != nil {
+			break
+		}
+		if order.Uint32(head[4:]) != crc32.Update(crc32.Checksum(head[:4], crcTable), crcTable, body) {
+			break
+		}
+		if err = f.apply(tree, body); err != nil {
+			return
+		}
+		intact += walHeaderSize + int64(len(body))
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return
+}
+
+// apply applies one record to the tree, the same way the modification did before it was logged.
+func (f *bpFile[K, V]) apply(tree *BpTreeG[K, V], body []byte) (err error) {
+	rec, err := f.decodeRecord(body)
+	if err != nil {
+		return
+	}
+	switch rec.kind {
+	case walInsert:
+		if _, found := tree.root.search(tree.cfg, rec.key); tree.cfg.unique && found {
+			return
+		}
+		err = tree.insert(BpItemG[K, V]{Key: rec.key, Val: rec.val})
+	case walRemove:
+		_, _, _, err = tree.delAndDir(BpItemG[K, V]{Key: rec.key})
+	case walUpsert:
+		_, _, err = tree.upsert(rec.key, rec.val)
+	case walRemoveNth:
+		tree.deleteToLeft(rec.key, rec.skip)
+	case walDeleteRange:
+		tree.deleteRange(rec.key, rec.to)
+	case walBatch:
+		_, _, err = tree.applyBatch(rec.ops)
+	case walCompact:
+		tree.compact()
+	case walLoad:
+		tree.load(rec.items, rec.fill)
+	}
+	tree.version++
+	return
+}
+
+// logRecord appends the record of a modification before it is applied, the caller holds the lock of the tree.
+// It returns nothing to wait for when the tree has no log.
+func (f *bpFile[K, V]) logRecord(rec walRecord[K, V]) (wait walWait, err error) {
+	if f == nil || f.log == nil {
+		return
+	}
+	order := f.header.order
+	buf, err := f.encodeRecord(append(make([]byte, walHeaderSize, 64), rec.kind), rec)
+	if err != nil {
+		return
+	}
+	order.PutUint32(buf, uint32(len(buf)-walHeaderSize))
+	order.PutUint32(buf[4:], crc32.Update(crc32.Checksum(buf[:4], crcTable), crcTable, buf[walHeaderSize:]))
+	wait.log = f.log
+	wait.lsn, err = f.log.append(buf)
+	return
+}
+
+// encodeRecord appends the fields of the record after its kind, the keys and the values the same way as in a page.
+func (f *bpFile[K, V]) encodeRecord(buf []byte, rec walRecord[K, V]) (_ []byte, err error) {
+	order := f.header.order
+	switch rec.kind {
+	case walInsert, walUpsert:
+		if buf, err = f.appendKey(buf, rec.key); err != nil {
+			return
+		}
+		return f.appendVal(buf, rec.val)
+	case walRemove:
+		return f.appendKey(buf, rec.key)
+	case walRemoveNth:
+		if buf, err = f.appendKey(buf, rec.key); err != nil {
+			return
+		}
+		return order.AppendUint32(buf, uint32(rec.skip)), nil
+	case walDeleteRange:
+		if buf, err = f.appendKey(buf, rec.key); err != nil {
+			return
+		}
+		return f.appendKey(buf, rec.to)
+	case walBatch:
+		buf = order.AppendUint32(buf, uint32(len(rec.ops)))
+		for _, op := range rec.ops {
+			if buf, err = f.appendKey(append(buf, byte(op.Kind)), op.Item.Key); err != nil {
+				return
+			}
+			if buf, err = f.appendVal(buf, op.Item.Val); err != nil {
+				return
+			}
+		}
+	case walLoad:
+		buf = order.AppendUint32(order.AppendUint64(buf, math.Float64bits(rec.fill)), uint32(len(rec.items)))
+		for _, item := range rec.items {
+			if buf, err = f.appendKey(buf, item.Key); err != nil {
+				return
+			}
+			if buf, err = f.appendVal(buf, item.Val); err != nil {
+				return
+			}
+		}
+	}
+	return buf, nil
+}
+
+// decodeRecord reads the record that encodeRecord wrote.
+func (f *bpFile[K, V]) decodeRecord(body []byte) (rec walRecord[K, V], err error) {
+	r := &pageReader{buf: body, order: f.header.order}
+	switch rec.kind = r.uint8(); rec.kind {
+	case walInsert, walUpsert:
+		if rec.key, err = f.readKey(r); err == nil {
+			rec.val, err = f.readVal(r)
+		}
+	case walRemove:
+		rec.key, err = f.readKey(r)
+	case walRemoveNth:
+		rec.key, err = f.readKey(r)
+		rec.skip = int(r.uint32())
+	case walDeleteRange:
+		if rec.key, err = f.readKey(r); err == nil {
+			rec.to, err = f.readKey(r)
+		}
+	case walBatch:
+		rec.ops = make([]OpG[K, V], min(int(r.uint32()), len(body)))
+		for i := 0; i < len(rec.ops) && err == nil; i++ {
+			rec.ops[i].Kind = OpKind(r.uint8())
+			if rec.ops[i].Item.Key, err = f.readKey(r); err == nil {
+				rec.ops[i].Item.Val, err = f.readVal(r)
+			}
+		}
+	case walCompact:
+	case walLoad:
+		rec.fill = math.Float64frombits(r.uint64())
+		rec.items = make([]BpItemG[K, V], min(int(r.uint32()), len(body)))
+		for i := 0; i < len(rec.items) && err == nil; i++ {
+			if rec.items[i].Key, err = f.readKey(r); err == nil {
+				rec.items[i].Val, err = f.readVal(r)
+			}
+		}
+	default:
+		err = fmt.Errorf("%w: a log record of kind %d", ErrCorruptPage, rec.kind)
+	}
+	if err == nil {
+		err = r.err
+	}
+	return
+}
+
+// append adds the record to the pending ones and numbers it.
+func (log *bpLog) append(record []byte) (lsn uint64, err error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if log.err != nil {
+		return 0, log.err
+	}
+	log.pending = append(log.pending, record...)
+	log.size += int64(len(record))
+	log.appended++
+	return log.appended, nil
+}
+
+// commit waits until the record is written, and synced with SyncAlways.
+// The first writer takes all the pending records, the writers arriving in the meantime wait for it
+// and are served together by the next one. (组提交，一次 fsync 服务多个写入者)
+func (log *bpLog) commit(lsn uint64) (err error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	for log.written < lsn && log.err == nil {
+		if log.writing {
+			log.cond.Wait()
+			continue
+		}
+
+		// Become the writer of the pending records.
+		log.writing = true
+		records, upto, file := log.pending, log.appended, log.file
+		log.pending = nil
+		log.writes++
+		log.mutex.Unlock()
+		_, err = file.Write(records)
+		if err == nil && log.policy == SyncAlways {
+			err = file.Sync()
+		}
+		log.mutex.Lock()
+		log.writing = false
+		if err != nil {
+			log.err = fmt.Errorf("writing the write-ahead log: %w", err)
+		} else {
+			log.written = max(log.written, upto)
+		}
+		log.cond.Broadcast()
+	}
+	if log.written < lsn {
+		err = log.err
+	}
+	return
+}
+
+// position returns the segment and the bytes of the records in it so far.
+func (log *bpLog) position() (segment uint64, offset int64) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	return log.segment, log.size
+}
+
+// retain keeps the segments from the one of a checkpoint on, Flush no longer removes them.
+func (log *bpLog) retain(segment uint64) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	log.keep = max(log.keep, segment)
+}
+
+// kept returns the first segment Flush keeps, zero without a checkpoint.
+func (log *bpLog) kept() uint64 {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	return log.keep
+}
+
+// syncEvery syncs the segment every interval until the log is closed.
+func (log *bpLog) syncEvery(interval time.Duration) {
+	defer close(log.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-log.stop:
+			return
+		case <-ticker.C:
+			log.sync()
+		}
+	}
+}
+
+// sync syncs the segment without holding the mutex, so that the records can still be appended in the meantime.
+// It takes the place of the writer, rotate and close wait for it before they touch the file.
+func (log *bpLog) sync() {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	for log.writing {
+		log.cond.Wait()
+	}
+	if log.err != nil {
+		return
+	}
+
+	// ⚠️ The fsync runs without the mutex.
+	log.writing = true
+	file := log.file
+	log.mutex.Unlock()
+	err := file.Sync()
+	log.mutex.Lock()
+	log.writing = false
+	if err != nil {
+		log.err = fmt.Errorf("syncing the write-ahead log: %w", err)
+	}
+	log.cond.Broadcast()
+}
+
+// rotate starts the segment of the version Flush has just written, the old records are part of it now.
+// The caller holds the lock of the tree, so no record is appended in the meantime.
+func (log *bpLog) rotate(segment uint64) (err error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	// Wait for the writer of the old segment.
+	for log.writing {
+		log.cond.Wait()
+	}
+
+	// A checkpoint replays the old segment later, so the records nobody has written yet are written now.
+	// Without a checkpoint, they are only part of the new version. (检查点需要完整的旧日志段)
+	if log.keep != 0 && log.err == nil && len(log.pending) > 0 {
+		if _, err = log.file.Write(log.pending); err == nil {
+			err = log.file.Sync()
+		}
+		if err != nil {
+			return
+		}
+	}
+	file, err := os.OpenFile(segmentPath(log.path, segment), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, filePermission)
+	if err != nil {
+		return
+	}
+	_ = log.file.Close()
+	log.file, log.segment, log.pending, log.size, log.written, log.err = file, segment, nil, 0, log.appended, nil
+	log.cond.Broadcast()
+	return
+}
+
+// close stops the syncing and closes the segment, the pending records are part of the last version already.
+func (log *bpLog) close() (err error) {
+	if log.stop != nil {
+		close(log.stop)
+		<-log.stopped
+	}
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	for log.writing {
+		log.cond.Wait()
+	}
+	log.written = log.appended
+	log.cond.Broadcast()
+	return log.file.Close()
+}
+
+// removeSegments removes the segments of the tree file at path outside from..to, every segment when from is greater.
+func removeSegments(path string, from, to uint64) (err error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	prefix := filepath.Base(path) + ".wal."
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		if segment, parseErr := strconv.ParseUint(name, 10, 64); parseErr == nil && (segment < from || segment > to) {
+			if err = os.Remove(filepath.Join(filepath.Dir(path), entry.Name())); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
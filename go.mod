@@ -1,6 +1,6 @@
 module github.com/panhongrainbow/go-algorithm
 
-go 1.22.4
+go 1.23
 
 require (
 	github.com/google/uuid v1.6.0
@@ -1 +1,3 @@
+//go:build linux
+
 package utilhub